@@ -9,7 +9,9 @@ import (
 	_ "github.com/shalb/cluster.dev/internal/secrets/aws_secretmanager"
 	_ "github.com/shalb/cluster.dev/internal/secrets/sops"
 	_ "github.com/shalb/cluster.dev/internal/units/shell/common"
+	_ "github.com/shalb/cluster.dev/internal/units/shell/helm_cli"
 	_ "github.com/shalb/cluster.dev/internal/units/shell/k8s_manifest"
+	_ "github.com/shalb/cluster.dev/internal/units/shell/kustomize"
 	_ "github.com/shalb/cluster.dev/internal/units/shell/terraform/helm"
 	_ "github.com/shalb/cluster.dev/internal/units/shell/terraform/kubernetes"
 	_ "github.com/shalb/cluster.dev/internal/units/shell/terraform/module"
@@ -25,6 +25,7 @@ require (
 	github.com/mitchellh/reflectwalk v1.0.2
 	github.com/olekukonko/tablewriter v0.0.5
 	github.com/paulrademacher/climenu v0.0.0-20151110221007-a1afbb4e378b
+	github.com/santhosh-tekuri/jsonschema/v5 v5.3.1
 	github.com/spf13/cobra v1.7.0
 	github.com/tj/go-spin v1.1.0
 	github.com/zclconf/go-cty v1.14.1
@@ -33,7 +33,7 @@ func (f *Factory) New(cnf []byte, name string, p *project.Project) (project.Back
 		bk.Path = filepath.Join(config.Global.ProjectConfigsPath, bk.Path)
 	}
 	isDir, err := utils.CheckDir(bk.Path)
-	if isDir {
+	if isDir || p.ReadOnly() {
 		return &bk, nil
 	}
 
@@ -16,7 +16,7 @@ var applyCmd = &cobra.Command{
 	Short:         "Deploys or updates infrastructure according to project configuration",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		project, err := project.LoadProjectFull()
-		if utils.GetEnv("CDEV_COLLECT_USAGE_STATS", "true") != "false" {
+		if utils.GetEnv("CDEV_COLLECT_USAGE_STATS", "true") != "false" && !config.Global.Offline {
 			log.Infof("Sending usage statistic. To disable statistics collection, export the CDEV_COLLECT_USAGE_STATS=false environment variable")
 		}
 		if err != nil {
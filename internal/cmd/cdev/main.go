@@ -24,6 +24,13 @@ func init() {
 	rootCmd.PersistentFlags().IntVar(&config.Global.MaxParallel, "parallelism", 3, "Max parallel threads for units applying")
 	rootCmd.PersistentFlags().BoolVar(&config.Global.TraceLog, "trace", false, "Print functions trace info in logs")
 	rootCmd.PersistentFlags().BoolVar(&config.Global.NoColor, "no-color", false, "Turn off colored output")
+	rootCmd.PersistentFlags().StringVar(&config.Global.Env, "env", "", "Select environment overlay from 'envs/<env>' dir")
+	rootCmd.PersistentFlags().StringVar(&config.Global.ProjectConfig, "project-file", "", "Use this file as project config instead of 'project.yaml'")
+	rootCmd.PersistentFlags().StringArrayVar(&config.Global.Vars, "var", []string{}, "Set stack variable, format: 'stack_name.variable=value'. Can be used multiple times")
+	rootCmd.PersistentFlags().StringArrayVar(&config.Global.VarFiles, "var-file", []string{}, "Set stacks variables from yaml/json file with stack names as top level keys. Can be used multiple times")
+	rootCmd.PersistentFlags().BoolVar(&config.Global.Locked, "locked", false, "Fail if cdev.lock is stale instead of updating it")
+	rootCmd.PersistentFlags().BoolVar(&config.Global.Offline, "offline", false, "Offline mode: skip version check and usage stats, use only cached templates, modules and files. Can be set with CDEV_OFFLINE=true")
+	rootCmd.PersistentFlags().StringVar(&config.Global.ProviderMirror, "provider-mirror", "", "Local dir with terraform providers mirror. Can be set with CDEV_PROVIDER_MIRROR")
 	rootCmd.PersistentFlags().BoolP("version", "v", false, "Print client version")
 	rootCmd.PersistentFlags().BoolP("help", "h", false, "Show this help output")
 	_ = rootCmd.PersistentFlags().MarkHidden("trace")
@@ -67,8 +74,10 @@ func Run() {
 		st.ProjectID = "null"
 		st.BackendType = "null"
 	}
-	exporter := utils.StatsExporter{}
-	_ = exporter.PushStats(st)
+	if !config.Global.Offline {
+		exporter := utils.StatsExporter{}
+		_ = exporter.PushStats(st)
+	}
 	if extendedErr.Err != nil {
 		log.Fatalf("Fatal error: %v", err.Error())
 	}
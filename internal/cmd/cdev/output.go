@@ -25,6 +25,10 @@ var outputCmd = &cobra.Command{
 		if err != nil {
 			log.Fatalf("Fatal error: outputs: print %v", err.Error())
 		}
+		err = project.OwnState.PrintStackOutputs()
+		if err != nil {
+			log.Fatalf("Fatal error: outputs: print stack outputs %v", err.Error())
+		}
 	},
 }
 
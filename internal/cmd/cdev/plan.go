@@ -33,4 +33,6 @@ func init() {
 	rootCmd.AddCommand(planCmd)
 	// planCmd.Flags().BoolVar(&config.Global.ShowTerraformPlan, "tf-plan", false, "Also show units terraform plan if possible.")
 	planCmd.Flags().BoolVar(&config.Global.IgnoreState, "force", false, "Show plan (if set tf-plan) even if the state has not changed.")
+	planCmd.Flags().BoolVar(&config.Global.LivePlan, "live", false, "Also compare units with live objects (e.g. server-side dry-run diff for k8s-manifest units, helm diff for helm-cli units).")
+	planCmd.Flags().BoolVar(&config.Global.DriftCheck, "drift", false, "Fail if live objects of unchanged units differ from the last applied configuration. Implies --live.")
 }
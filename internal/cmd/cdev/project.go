@@ -18,11 +18,13 @@ var projectCmd = &cobra.Command{
 	Short: "Manage projects",
 }
 var listAllTemplates bool
+var showVariables bool
 
 func init() {
 	rootCmd.AddCommand(projectCmd)
 	projectCmd.AddCommand(projectInfo)
 	projectCmd.AddCommand(projectCreate)
+	projectInfo.Flags().BoolVar(&showVariables, "vars", false, "Show effective stacks variables and their sources")
 	projectCreate.Flags().BoolVar(&config.Global.Interactive, "interactive", false, "Use interactive mode for project generation")
 	projectCreate.Flags().BoolVar(&listAllTemplates, "list-templates", false, "Show all available templates for project generation")
 }
@@ -40,6 +42,9 @@ var projectInfo = &cobra.Command{
 		}
 		log.Info("Project info:")
 		p.PrintInfo()
+		if showVariables {
+			p.PrintVariables()
+		}
 		log.Infof("Project configuration check: %v", color.Style{color.FgGreen, color.OpBold}.Sprintf("valid"))
 	},
 }
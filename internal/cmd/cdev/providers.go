@@ -0,0 +1,40 @@
+package cdev
+
+import (
+	"github.com/apex/log"
+	"github.com/shalb/cluster.dev/internal/config"
+	"github.com/shalb/cluster.dev/internal/project"
+	"github.com/spf13/cobra"
+)
+
+var providersLockUpgrade bool
+
+// providersCmd represents the providers command
+var providersCmd = &cobra.Command{
+	Use:   "providers",
+	Short: "Terraform providers operations",
+}
+
+// providersLockCmd represents the providers lock command
+var providersLockCmd = &cobra.Command{
+	Use:   "lock",
+	Short: "Creates the project-wide .terraform.lock.hcl with providers used by all terraform-based units",
+	Run: func(cmd *cobra.Command, args []string) {
+		config.Global.IgnoreState = true
+		p, err := project.LoadProjectFull()
+		if err != nil {
+			log.Fatalf("Fatal error: providers lock: %v", err.Error())
+		}
+		err = p.LockProviders(providersLockUpgrade)
+		if err != nil {
+			log.Fatalf("Fatal error: providers lock: %v", err.Error())
+		}
+		log.Infof("Providers are locked in %v", project.ProvidersLockFileName)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(providersCmd)
+	providersCmd.AddCommand(providersLockCmd)
+	providersLockCmd.Flags().BoolVar(&providersLockUpgrade, "upgrade", false, "Ignore the current lock and select the newest provider versions allowed by constraints")
+}
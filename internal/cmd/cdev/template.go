@@ -0,0 +1,82 @@
+package cdev
+
+import (
+	"github.com/apex/log"
+	"github.com/shalb/cluster.dev/internal/config"
+	"github.com/shalb/cluster.dev/internal/project"
+	"github.com/shalb/cluster.dev/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+// templateCmd represents the template command
+var templateCmd = &cobra.Command{
+	Use:   "template",
+	Short: "Stack templates operations",
+}
+
+// templateUpdateCmd represents the template update command
+var templateUpdateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Updates commits and digests of stack templates and terraform modules sources pinned in cdev.lock to the latest versions of their refs",
+	Run: func(cmd *cobra.Command, args []string) {
+		if config.Global.Locked {
+			log.Fatalf("Fatal error: template update: can't be used with --locked option")
+		}
+		config.Global.IgnoreState = true
+		config.Global.UpdateLock = true
+		p, err := project.LoadProjectFull()
+		if err != nil {
+			log.Fatalf("Fatal error: template update: %v", err.Error())
+		}
+		log.Info("Locked sources:")
+		p.PrintLockedSources()
+	},
+}
+
+// templatePushCmd represents the template push command
+var templatePushCmd = &cobra.Command{
+	Use:     "push <template_dir> <oci://registry/repository:tag>",
+	Short:   "Publishes the stack template dir to the registry as an OCI artifact",
+	Args:    cobra.ExactArgs(2),
+	Example: "cdev template push ./template oci://ghcr.io/my-org/templates/aws-eks:1.0.0",
+	Run: func(cmd *cobra.Command, args []string) {
+		if !utils.IsDir(args[0]) {
+			log.Fatalf("Fatal error: template push: '%v' is not a dir", args[0])
+		}
+		digest, err := utils.PushOCITemplate(args[0], args[1])
+		if err != nil {
+			log.Fatalf("Fatal error: template push: %v", err.Error())
+		}
+		log.Infof("Pushed %v, digest: %v", args[1], digest)
+	},
+}
+
+// templateTestCmd represents the template test command
+var templateTestCmd = &cobra.Command{
+	Use:   "test [template_dir]",
+	Short: "Renders the stack template with test fixtures and compares units with golden files",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		templateDir := "."
+		if len(args) > 0 {
+			templateDir = args[0]
+		}
+		results, err := project.TestTemplate(templateDir, updateGoldenFiles)
+		if err != nil {
+			log.Fatalf("Fatal error: template test: %v", err.Error())
+		}
+		if failed := project.PrintTemplateTestResults(results, updateGoldenFiles); failed > 0 {
+			log.Fatalf("Fatal error: template test: %v of %v tests failed", failed, len(results))
+		}
+	},
+}
+
+var updateGoldenFiles bool
+
+func init() {
+	rootCmd.AddCommand(templateCmd)
+	templateCmd.AddCommand(templateUpdateCmd)
+	templateCmd.AddCommand(templatePushCmd)
+	templateCmd.AddCommand(templateTestCmd)
+	templateTestCmd.Flags().BoolVar(&updateGoldenFiles, "update", false, "Rewrite golden files with rendered files")
+}
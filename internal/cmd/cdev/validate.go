@@ -14,7 +14,11 @@ var projectValidate = &cobra.Command{
 	Short: "Validates the configuration files in a project directory, referring only to the configuration and not accessing remote state bucket",
 	Run: func(cmd *cobra.Command, args []string) {
 		config.Global.IgnoreState = true
-		_, err := project.LoadProjectFull()
+		p, err := project.LoadProjectFull()
+		if err != nil {
+			log.Fatalf("Project configuration check: %v\n%v", color.Style{color.FgGreen, color.OpBold}.Sprintf("fail"), err.Error())
+		}
+		err = p.Validate()
 		if err != nil {
 			log.Fatalf("Project configuration check: %v\n%v", color.Style{color.FgGreen, color.OpBold}.Sprintf("fail"), err.Error())
 		}
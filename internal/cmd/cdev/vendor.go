@@ -0,0 +1,35 @@
+package cdev
+
+import (
+	"github.com/apex/log"
+	"github.com/shalb/cluster.dev/internal/config"
+	"github.com/shalb/cluster.dev/internal/project"
+	"github.com/spf13/cobra"
+)
+
+// vendorCmd represents the vendor command
+var vendorCmd = &cobra.Command{
+	Use:   "vendor",
+	Short: "Downloads remote stack templates, terraform modules, helm charts and manifests used by the project to the 'vendor' dir",
+	Run: func(cmd *cobra.Command, args []string) {
+		if config.Global.Offline {
+			log.Fatalf("Fatal error: vendor: can't be used in offline mode")
+		}
+		config.Global.IgnoreState = true
+		config.Global.Vendoring = true
+		p, err := project.LoadProjectFull()
+		if err != nil {
+			log.Fatalf("Fatal error: vendor: %v", err.Error())
+		}
+		err = p.Vendor()
+		if err != nil {
+			log.Fatalf("Fatal error: vendor: %v", err.Error())
+		}
+		log.Info("Vendored sources:")
+		p.PrintVendoredSources()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(vendorCmd)
+}
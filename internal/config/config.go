@@ -45,6 +45,7 @@ type ConfSpec struct {
 	ProjectConfigsPath string
 	LogLevel           string
 	ProjectConfig      string
+	Env                string
 	Version            string
 	Build              string
 	WorkDir            string
@@ -56,15 +57,26 @@ type ConfSpec struct {
 	OptFooTest         bool
 	IgnoreState        bool
 	// ShowTerraformPlan  bool
-	StateCacheDir     string
-	TemplatesCacheDir string
-	CacheDir          string
-	NoColor           bool
-	Force             bool
-	Interactive       bool
-	OutputJSON        bool
-	Targets           []string
-	TargetsExclude    []string
+	LivePlan           bool
+	DriftCheck         bool
+	StateCacheDir      string
+	TemplatesCacheDir  string
+	CacheDir           string
+	NoColor            bool
+	Force              bool
+	Interactive        bool
+	OutputJSON         bool
+	Targets            []string
+	TargetsExclude     []string
+	Vars               []string
+	Locked             bool
+	Offline            bool
+	Vendoring          bool
+	ProviderMirror     string
+	DownloadsCacheDir  string
+	TerraformCLIConfig string
+	UpdateLock         bool
+	VarFiles           []string
 }
 
 // Global config for executor.
@@ -86,9 +98,27 @@ func InitConfig() {
 	logging.InitLogLevel(Global.LogLevel, Global.TraceLog)
 	Global.ProjectConfigsPath = curPath
 	Global.WorkDir = filepath.Join(curPath, ".cluster.dev")
+	if Global.Env != "" {
+		// Keep state, cache and lock of each environment separately.
+		Global.WorkDir = filepath.Join(Global.WorkDir, Global.Env)
+	}
 	Global.CacheDir = filepath.Join(Global.WorkDir, "cache/")
 	Global.StateCacheDir = filepath.Join(Global.WorkDir, "cache/")
 	Global.TemplatesCacheDir = filepath.Join(Global.WorkDir, "templates")
+	Global.DownloadsCacheDir = filepath.Join(Global.WorkDir, "downloads")
+	if offline := os.Getenv("CDEV_OFFLINE"); offline == "true" || offline == "1" {
+		Global.Offline = true
+	}
+	if Global.ProviderMirror == "" {
+		Global.ProviderMirror = os.Getenv("CDEV_PROVIDER_MIRROR")
+	}
+	if Global.ProviderMirror != "" {
+		Global.ProviderMirror, err = filepath.Abs(Global.ProviderMirror)
+		if err != nil {
+			log.Fatalf("Provider mirror path: %s", err.Error())
+		}
+		Global.TerraformCLIConfig = filepath.Join(Global.WorkDir, "terraform.rc")
+	}
 	usr, err := user.Current()
 	if err != nil {
 		log.Fatal(err.Error())
@@ -75,15 +75,7 @@ func (p *Project) readBackendObj(obj ObjectData) error {
 	if !ok {
 		return fmt.Errorf("'%v': must contain field 'provider'", name)
 	}
-	rawSpec, err := yaml.Marshal(&spec)
-	if err != nil {
-		return err
-	}
-	factory, exists := BackendsFactories[provider]
-	if !exists {
-		return fmt.Errorf("'%v': provider does not found: %v", name, provider)
-	}
-	b, err := factory.New(rawSpec, name, p)
+	b, err := newBackend(name, provider, spec, p)
 	if err != nil {
 		return err
 	}
@@ -92,6 +84,19 @@ func (p *Project) readBackendObj(obj ObjectData) error {
 	return nil
 }
 
+// newBackend creates backend of the provider with spec for the project.
+func newBackend(name, provider string, spec interface{}, p *Project) (Backend, error) {
+	rawSpec, err := yaml.Marshal(&spec)
+	if err != nil {
+		return nil, err
+	}
+	factory, exists := BackendsFactories[provider]
+	if !exists {
+		return nil, fmt.Errorf("'%v': provider does not found: %v", name, provider)
+	}
+	return factory.New(rawSpec, name, p)
+}
+
 func addDefaultBackend(p *Project) error {
 	if _, exists := p.Backends["default"]; exists {
 		return fmt.Errorf("read backends: name 'default' is reserved, use another backend name")
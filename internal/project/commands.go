@@ -244,7 +244,11 @@ func (p *Project) Plan() (*graph, error) {
 	if err != nil {
 		return nil, err
 	}
+	p.printRemoteProjectOutputs()
 	showPlanResults(planningSt)
+	if config.Global.DriftCheck {
+		return planningSt, checkDrift(planningSt.planningUnits)
+	}
 	return planningSt, nil
 }
 
@@ -255,6 +259,9 @@ func (p *Project) planDestroy(opStatus *ProjectPlanningStatus) {
 			continue
 		}
 		diff := utils.Diff(md.GetDiffData(), nil, true)
+		if err := p.CheckUnitDisabled(md.Key()); err != nil {
+			diff = colors.Fmt(colors.Yellow).Sprintf("- Will be destroyed: %v", err.Error()) + "\n" + diff
+		}
 		opStatus.Add(md, Destroy, diff, md.IsTainted())
 	}
 }
@@ -297,6 +304,9 @@ func (p *Project) buildPlan() (resGraph *graph, err error) {
 		for _, u := range p.UnitsSlice() {
 			planningStatus.Add(u, Apply, utils.Diff(nil, u.GetDiffData(), true), false)
 		}
+		if err = p.planLive(planningStatus); err != nil {
+			return nil, err
+		}
 		return planningStatus.BuildGraph()
 	}
 	p.planDestroy(planningStatus)
@@ -347,6 +357,9 @@ func (p *Project) buildPlan() (resGraph *graph, err error) {
 	// 		break
 	// 	}
 	// }
+	if err = p.planLive(planningStatus); err != nil {
+		return nil, err
+	}
 	// Check graph and set sequence indexes
 	resGraph, err = planningStatus.BuildGraph()
 	if err != nil {
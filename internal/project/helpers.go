@@ -47,6 +47,8 @@ type UnitPlanningStatus struct {
 	Operation UnitOperation
 	IsTainted bool
 	Index     int
+	// LivePlan is set for units which support live plan, if it is enabled.
+	LivePlan *LivePlan
 }
 
 type ProjectPlanningStatus struct {
@@ -376,6 +378,9 @@ func showPlanResults(opStatus *graph) error {
 	fmt.Println(colors.Fmt(colors.WhiteBold).Sprint("Plan results:"))
 
 	if opStatus.Len() == 0 {
+		for _, unit := range opStatus.planningUnits.Slice() {
+			printLivePlan(unit)
+		}
 		fmt.Println(colors.Fmt(colors.WhiteBold).Sprint("No changes, nothing to do."))
 		return nil
 	}
@@ -413,6 +418,8 @@ func showPlanResults(opStatus *graph) error {
 			}
 			unchangedString += RenderUnitPlanningString(unit)
 		}
+		printLivePlan(unit)
+		printRemoteProjectDependencies(unit)
 	}
 
 	if opStatus.planningUnits.OperationFilter(Apply).Len() > 0 {
@@ -437,11 +444,25 @@ func showPlanResults(opStatus *graph) error {
 	return nil
 }
 
+// printRemoteProjectDependencies prints outputs of referenced projects used by the unit.
+func printRemoteProjectDependencies(us *UnitPlanningStatus) {
+	if us.Operation == Destroy {
+		return
+	}
+	deps := us.UnitPtr.Project().RemoteProjectDependencies(us.UnitPtr.Key())
+	if len(deps) > 0 {
+		log.Infof("Unit '%v' depends on referenced projects outputs: %v", us.UnitPtr.Key(), strings.Join(deps, ", "))
+	}
+}
+
 func RenderUnitPlanningString(uStatus *UnitPlanningStatus) string {
 	keyForRender := uStatus.UnitPtr.Key()
 	if config.Global.LogLevel == "debug" {
 		keyForRender += fmt.Sprintf("(%v)", uStatus.Index)
 	}
+	if uStatus.LivePlan != nil {
+		keyForRender += fmt.Sprintf("[%v]", uStatus.LivePlan.Summary())
+	}
 	switch uStatus.Operation {
 	case Update:
 		if uStatus.IsTainted {
@@ -493,7 +514,8 @@ func checkUnitPath(u Unit, path string) bool {
 		return u.Stack().Name == pathSpl[0]
 	}
 	if len(pathSpl) == 2 {
-		return (u.Stack().Name == pathSpl[0] && u.Name() == pathSpl[1])
+		// Path without key ('stack.unit') includes all instances of the unit created by 'for_each' or 'count'.
+		return (u.Stack().Name == pathSpl[0] && (u.Name() == pathSpl[1] || strings.HasPrefix(u.Name(), pathSpl[1]+"[")))
 	}
 
 	return false
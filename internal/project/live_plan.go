@@ -0,0 +1,119 @@
+package project
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/apex/log"
+	"github.com/shalb/cluster.dev/internal/config"
+	"github.com/shalb/cluster.dev/pkg/colors"
+)
+
+// LivePlan describes changes of live objects managed by the unit, e.g. the result of server-side dry-run diff.
+type LivePlan struct {
+	Created []string
+	Changed []string
+	Deleted []string
+	// Diff is the detailed diff of live objects.
+	Diff string
+}
+
+// IsEmpty returns true if live objects match the unit configuration.
+func (l *LivePlan) IsEmpty() bool {
+	return l == nil || len(l.Created)+len(l.Changed)+len(l.Deleted) == 0
+}
+
+// Summary returns counts of created, changed and deleted objects, e.g. '+1/~2/-0'.
+func (l *LivePlan) Summary() string {
+	if l == nil {
+		return ""
+	}
+	return fmt.Sprintf("+%d/~%d/-%d", len(l.Created), len(l.Changed), len(l.Deleted))
+}
+
+// LivePlanResult keeps the result of the unit live plan. Units embed it to implement UnitLivePlanner.
+type LivePlanResult struct {
+	livePlan *LivePlan
+}
+
+// LivePlan returns the result of the unit Plan.
+func (r *LivePlanResult) LivePlan() *LivePlan {
+	return r.livePlan
+}
+
+// SetLivePlan saves the result of the unit Plan.
+func (r *LivePlanResult) SetLivePlan(plan *LivePlan) {
+	r.livePlan = plan
+}
+
+// UnitLivePlanner is an optional interface for units which compare the configuration with live objects (used by
+// 'cdev plan --live'). Plan() runs the comparison of the built unit, LivePlan() returns its result.
+type UnitLivePlanner interface {
+	LivePlan() *LivePlan
+}
+
+// UnitRenderPlanner is an optional interface for live planners which compare objects rendered by cdev with the last
+// apply, without access to live objects (e.g. manifests of the local helm chart). RenderPlan returns true if the unit
+// has objects to render, such units are planned by every 'cdev plan', not only with '--live'.
+type UnitRenderPlanner interface {
+	UnitLivePlanner
+	RenderPlan() bool
+}
+
+// planLive builds and plans units which implement UnitLivePlanner. Without live plan and drift check modes only
+// render planners are planned. Units with unresolved dependencies outputs are skipped with the warning. Failed plans
+// of unchanged units are errors in the drift check mode.
+func (p *Project) planLive(planningStatus *ProjectPlanningStatus) error {
+	liveMode := config.Global.LivePlan || config.Global.DriftCheck
+	for _, us := range planningStatus.OperationFilter(Apply, Update, NotChanged).Slice() {
+		planner, ok := us.UnitPtr.(UnitLivePlanner)
+		if !ok {
+			continue
+		}
+		if !liveMode {
+			renderPlanner, ok := us.UnitPtr.(UnitRenderPlanner)
+			if !ok || !renderPlanner.RenderPlan() {
+				continue
+			}
+		}
+		if err := us.UnitPtr.Build(); err != nil {
+			log.Warnf("Unit '%v': live plan skipped: %v", us.UnitPtr.Key(), err.Error())
+			continue
+		}
+		if err := us.UnitPtr.Plan(); err != nil {
+			if config.Global.DriftCheck && us.Operation == NotChanged {
+				return fmt.Errorf("drift check: %w", err)
+			}
+			log.Warnf("Unit '%v': live plan failed: %v", us.UnitPtr.Key(), err.Error())
+			continue
+		}
+		us.LivePlan = planner.LivePlan()
+	}
+	return nil
+}
+
+// printLivePlan prints the detailed diff of live objects of the unit, if any.
+func printLivePlan(us *UnitPlanningStatus) {
+	if us.LivePlan.IsEmpty() {
+		return
+	}
+	log.Infof(colors.Fmt(colors.Yellow).Sprintf("Unit '%v': live objects differ: %v", us.UnitPtr.Key(), us.LivePlan.Summary()))
+	fmt.Printf("%v\n", us.LivePlan.Diff)
+}
+
+// checkDrift returns an error if live objects of unchanged units differ from the last applied configuration.
+func checkDrift(planningStatus *ProjectPlanningStatus) error {
+	drifted := []string{}
+	for _, us := range planningStatus.OperationFilter(NotChanged).Slice() {
+		if !us.LivePlan.IsEmpty() {
+			drifted = append(drifted, fmt.Sprintf("%v (%v)", us.UnitPtr.Key(), us.LivePlan.Summary()))
+		}
+	}
+	if len(drifted) == 0 {
+		log.Infof(colors.Fmt(colors.GreenBold).Sprint("No drift detected."))
+		return nil
+	}
+	sort.Strings(drifted)
+	return fmt.Errorf("drift detected, live objects differ from the last applied configuration: %v", strings.Join(drifted, ", "))
+}
@@ -0,0 +1,279 @@
+package project
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/apex/log"
+	"github.com/olekukonko/tablewriter"
+	"github.com/shalb/cluster.dev/internal/config"
+	"github.com/shalb/cluster.dev/pkg/utils"
+	"gopkg.in/yaml.v3"
+)
+
+const lockFileName = "cdev.lock"
+
+const lockFileHeader = "# This file is maintained by cdev, do not edit it manually.\n# Use 'cdev template update' to update locked commits and digests.\n"
+
+const (
+	lockedTemplateKind = "template"
+	lockedTfModuleKind = "tfmodule"
+)
+
+// LockedSource describes the source pinned to the git commit, archive checksum or OCI artifact digest.
+type LockedSource struct {
+	Kind   string `yaml:"kind"`
+	Commit string `yaml:"commit,omitempty"`
+	Digest string `yaml:"digest,omitempty"`
+	Hash   string `yaml:"hash"`
+}
+
+// lockFile describes cdev.lock file, sources are stored by urls used in the project.
+type lockFile struct {
+	Sources map[string]*LockedSource `yaml:"sources"`
+	// used contains sources used by the project, changed is true when sources are added or updated.
+	used    map[string]bool
+	changed bool
+}
+
+// readLockFile reads cdev.lock from the project dir. Missing file gives an empty lock.
+func (p *Project) readLockFile() error {
+	p.lock = &lockFile{
+		Sources: map[string]*LockedSource{},
+		used:    map[string]bool{},
+	}
+	data, err := os.ReadFile(filepath.Join(config.Global.WorkingDir, lockFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("reading %v: %w", lockFileName, err)
+	}
+	if err = yaml.Unmarshal(data, p.lock); err != nil {
+		return fmt.Errorf("reading %v: %v", lockFileName, utils.ResolveYamlError(data, err))
+	}
+	if p.lock.Sources == nil {
+		p.lock.Sources = map[string]*LockedSource{}
+	}
+	return nil
+}
+
+// getLockedGitSource downloads git source to the templates cache dir and checks out the commit from cdev.lock.
+// Sources which are not locked yet are added to the lock with the latest commit of the ref. Returns the dir of the
+// source content. Sources are locked by the source string used in the project, gitURL is the source in the format
+// supported by utils.ParseGitUrl.
+func (p *Project) getLockedGitSource(src, gitURL, kind string) (string, error) {
+	parsedRepoURL, err := utils.ParseGitUrl(gitURL)
+	if err != nil {
+		return "", err
+	}
+	folderName, err := utils.URLToFolderName(parsedRepoURL.RepoString)
+	if err != nil {
+		return "", err
+	}
+	os.Mkdir(config.Global.TemplatesCacheDir, os.ModePerm)
+	locked, isLocked, err := p.lockedSource(src)
+	if err != nil {
+		return "", err
+	}
+	commit := ""
+	if isLocked {
+		commit = locked.Commit
+	}
+	var dir, headCommit string
+	if config.Global.Offline {
+		dir, headCommit, err = utils.GetCachedTemplate(gitURL, config.Global.TemplatesCacheDir, folderName, commit)
+	} else {
+		dir, headCommit, err = utils.GetTemplateAtCommit(gitURL, config.Global.TemplatesCacheDir, folderName, commit)
+	}
+	if err != nil {
+		return "", err
+	}
+	if err = p.lockSource(src, isLocked, LockedSource{Kind: kind, Commit: headCommit}, dir); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// lockedSource returns the lock entry of the source. Returns an error if the source is not locked in locked mode.
+// In update mode sources are treated as not locked.
+func (p *Project) lockedSource(src string) (*LockedSource, bool, error) {
+	locked, isLocked := p.lock.Sources[src]
+	if config.Global.UpdateLock {
+		isLocked = false
+	}
+	if !isLocked && config.Global.Locked {
+		return nil, false, fmt.Errorf("%v is stale: source '%v' is not locked, run 'cdev template update'", lockFileName, src)
+	}
+	return locked, isLocked, nil
+}
+
+// lockSource checks the content hash of the source dir with the locked hash, or adds the source to the lock if it is
+// not locked yet.
+func (p *Project) lockSource(src string, isLocked bool, source LockedSource, dir string) error {
+	hash, err := utils.DirHash(dir)
+	if err != nil {
+		return fmt.Errorf("hash of '%v': %w", src, err)
+	}
+	revision := source.Commit
+	if revision == "" {
+		revision = source.Digest
+	}
+	p.lock.used[src] = true
+	if isLocked {
+		if hash != p.lock.Sources[src].Hash {
+			return fmt.Errorf("%v: content hash of '%v' (%v) does not match the locked hash, run 'cdev template update'", lockFileName, src, revision)
+		}
+		log.Debugf("Source '%v' is locked to %v", src, revision)
+		return nil
+	}
+	source.Hash = hash
+	if prev, exists := p.lock.Sources[src]; !exists || *prev != source {
+		p.lock.changed = true
+	}
+	p.lock.Sources[src] = &source
+	log.Debugf("Source '%v' is locked to %v", src, revision)
+	return nil
+}
+
+// getLockedArchiveSource downloads the archive, verifies it by the checksum from the url or from cdev.lock and
+// extracts it to the templates cache dir. Returns the dir of the source content.
+func (p *Project) getLockedArchiveSource(src string, archive utils.ArchiveSource, kind string) (string, error) {
+	locked, isLocked, err := p.lockedSource(src)
+	if err != nil {
+		return "", err
+	}
+	checksum := archive.Checksum
+	if checksum == "" && isLocked {
+		checksum = locked.Digest
+	}
+	data, err := utils.GetFileByUrlCached(archive.URL, filepath.Join(config.Global.TemplatesCacheDir, "archives"), config.Global.Offline)
+	if err != nil {
+		return "", fmt.Errorf("download archive '%v': %w", archive.URL, err)
+	}
+	if checksum != "" {
+		if err = utils.VerifyChecksum(data, checksum); err != nil {
+			return "", fmt.Errorf("archive '%v': %w", archive.URL, err)
+		}
+	}
+	digest := utils.Sha256Digest(data)
+	folderName, err := utils.URLToFolderName(archive.URL)
+	if err != nil {
+		return "", err
+	}
+	archiveDir := filepath.Join(config.Global.TemplatesCacheDir, folderName, strings.TrimPrefix(digest, "sha256:"))
+	if !utils.IsDir(archiveDir) {
+		if err = utils.ExtractArchive(data, archiveDir); err != nil {
+			os.RemoveAll(archiveDir)
+			return "", fmt.Errorf("archive '%v': %w", archive.URL, err)
+		}
+	}
+	dir := filepath.Join(archiveDir, archive.SubDir)
+	if err = p.lockSource(src, isLocked, LockedSource{Kind: kind, Digest: digest}, dir); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// getLockedOCISource pulls OCI artifact to the templates cache dir. The tag is resolved to the digest, which is
+// pinned in cdev.lock. Returns the dir of the source content.
+func (p *Project) getLockedOCISource(src, kind string) (string, error) {
+	ref, err := utils.ParseOCIReference(src)
+	if err != nil {
+		return "", err
+	}
+	locked, isLocked, err := p.lockedSource(src)
+	if err != nil {
+		return "", err
+	}
+	if isLocked {
+		ref.Reference = locked.Digest
+	}
+	folderName, err := utils.URLToFolderName(ref.Name())
+	if err != nil {
+		return "", err
+	}
+	var dir, digest string
+	if config.Global.Offline {
+		dir, err = utils.GetCachedOCITemplate(ref, config.Global.TemplatesCacheDir, folderName)
+		digest = ref.Reference
+	} else {
+		dir, digest, err = utils.PullOCITemplate(ref, config.Global.TemplatesCacheDir, folderName)
+	}
+	if err != nil {
+		return "", err
+	}
+	if err = p.lockSource(src, isLocked, LockedSource{Kind: kind, Digest: digest}, dir); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// LockTerraformSource pins git source of terraform module to the commit from cdev.lock. Other sources are returned
+// unchanged. cachedDir is the dir of the module in the templates cache, empty for not git sources.
+func (p *Project) LockTerraformSource(source string) (lockedSource, cachedDir string, err error) {
+	gitURL, isGit := utils.ParseTerraformGitSource(source)
+	if !isGit {
+		return source, "", nil
+	}
+	cachedDir, err = p.getLockedGitSource(source, gitURL, lockedTfModuleKind)
+	if err != nil {
+		return "", "", fmt.Errorf("lock module source: %w", err)
+	}
+	if err = p.addVendorDir(source, lockedTfModuleKind, cachedDir); err != nil {
+		return "", "", err
+	}
+	return utils.SetGitSourceRef(source, p.lock.Sources[source].Commit), cachedDir, nil
+}
+
+// saveLockFile writes cdev.lock if sources were added or updated. In update mode, sources which are not used by the
+// project are removed.
+func (p *Project) saveLockFile() error {
+	if config.Global.UpdateLock {
+		for src := range p.lock.Sources {
+			if !p.lock.used[src] {
+				delete(p.lock.Sources, src)
+				p.lock.changed = true
+			}
+		}
+	}
+	if !p.lock.changed {
+		return nil
+	}
+	lockFilePath := filepath.Join(config.Global.WorkingDir, lockFileName)
+	if len(p.lock.Sources) == 0 {
+		log.Infof("No git sources used, removing %v", lockFileName)
+		return os.Remove(lockFilePath)
+	}
+	buf := bytes.NewBufferString(lockFileHeader)
+	enc := yaml.NewEncoder(buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(p.lock); err != nil {
+		return err
+	}
+	log.Infof("Writing %v", lockFileName)
+	return os.WriteFile(lockFilePath, buf.Bytes(), 0644)
+}
+
+// PrintLockedSources prints sources from cdev.lock.
+func (p *Project) PrintLockedSources() {
+	sources := make([]string, 0, len(p.lock.Sources))
+	for src := range p.lock.Sources {
+		sources = append(sources, src)
+	}
+	sort.Strings(sources)
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Source", "Kind", "Commit/Digest"})
+	for _, src := range sources {
+		revision := p.lock.Sources[src].Commit
+		if revision == "" {
+			revision = p.lock.Sources[src].Digest
+		}
+		table.Append([]string{src, p.lock.Sources[src].Kind, revision})
+	}
+	table.Render()
+}
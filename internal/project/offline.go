@@ -0,0 +1,28 @@
+package project
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/apex/log"
+	"github.com/shalb/cluster.dev/internal/config"
+)
+
+// terraformCLIConfigTmpl configures terraform to install all providers from the local mirror only.
+const terraformCLIConfigTmpl = `# Generated by cdev, provider mirror is set with --provider-mirror option or CDEV_PROVIDER_MIRROR.
+provider_installation {
+  filesystem_mirror {
+    path = %q
+  }
+}
+`
+
+// writeTerraformCLIConfig writes terraform CLI config file which is passed to terraform units with TF_CLI_CONFIG_FILE.
+func writeTerraformCLIConfig() error {
+	isDir, err := os.Stat(config.Global.ProviderMirror)
+	if err != nil || !isDir.IsDir() {
+		return fmt.Errorf("provider mirror '%v' should be an existing dir", config.Global.ProviderMirror)
+	}
+	log.Debugf("Using terraform providers mirror: %v", config.Global.ProviderMirror)
+	return os.WriteFile(config.Global.TerraformCLIConfig, []byte(fmt.Sprintf(terraformCLIConfigTmpl, config.Global.ProviderMirror)), 0644)
+}
@@ -5,6 +5,8 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"sort"
+	"strings"
 	"sync"
 
 	"github.com/apex/log"
@@ -37,12 +39,19 @@ type Project struct {
 	name                string
 	SessionId           string
 	Units               map[string]Unit
+	DisabledUnits       map[string]string
 	Stacks              map[string]*Stack
 	Backends            map[string]Backend
 	UnitLinks           *UnitLinksT
 	secrets             map[string]Secret
 	configData          map[string]interface{}
 	configDataFile      []byte
+	envConfigDataFile   []byte
+	envFiles            map[string]bool
+	variablesOverrides  map[string][]stackVariablesOverride
+	lock                *lockFile
+	vendor              *vendorSpec
+	providersLock       []byte
 	objects             map[string][]ObjectData
 	objectsFiles        map[string][]byte
 	CodeCacheDir        string
@@ -55,27 +64,47 @@ type Project struct {
 	ProcessedUnitsCount uint
 	HupUnlockChan       chan os.Signal
 	NewVersionMessage   string
+	StackOutputs        map[string]map[string]interface{}
+	stackOutputMarkers  map[string]stackOutputRef
+	ProjectReferences   map[string]*ProjectReference
+	// RemoteProjectOutputs contains outputs of referenced projects used in this project.
+	RemoteProjectOutputs map[string]*RemoteProjectOutput
+	remoteProjectMarkers map[string]string
+	// readOnly is set for projects opened by ProjectReference.
+	readOnly bool
 }
 
 // NewEmptyProject creates new empty project. The configuration will not be loaded.
 func NewEmptyProject() *Project {
 
 	project := &Project{
-		SessionId:           utils.Md5(utils.RandString(64)),
-		Stacks:              make(map[string]*Stack),
-		Units:               make(map[string]Unit),
-		Backends:            make(map[string]Backend),
-		objects:             make(map[string][]ObjectData),
-		configData:          make(map[string]interface{}),
-		secrets:             make(map[string]Secret),
-		ProcessedUnitsCount: 0,
-		UnitLinks:           &UnitLinksT{},
+		SessionId:            utils.Md5(utils.RandString(64)),
+		Stacks:               make(map[string]*Stack),
+		Units:                make(map[string]Unit),
+		DisabledUnits:        make(map[string]string),
+		Backends:             make(map[string]Backend),
+		objects:              make(map[string][]ObjectData),
+		configData:           make(map[string]interface{}),
+		secrets:              make(map[string]Secret),
+		envFiles:             make(map[string]bool),
+		variablesOverrides:   make(map[string][]stackVariablesOverride),
+		ProcessedUnitsCount:  0,
+		StackOutputs:         make(map[string]map[string]interface{}),
+		stackOutputMarkers:   make(map[string]stackOutputRef),
+		ProjectReferences:    make(map[string]*ProjectReference),
+		RemoteProjectOutputs: make(map[string]*RemoteProjectOutput),
+		remoteProjectMarkers: make(map[string]string),
+		UnitLinks:            &UnitLinksT{},
 		RuntimeDataset: RuntimeData{
 			UnitsOutputs:    make(map[string]interface{}),
 			PrintersOutputs: make([]PrinterOutput, 0),
 		},
 		CodeCacheDir: config.Global.CacheDir,
 	}
+	if config.Global.Offline {
+		log.Debug("Offline mode, skip checking for newer releases")
+		return project
+	}
 	log.Info("Checking for newer releases...")
 	err := DiscoverCdevLastRelease()
 	if err != nil {
@@ -103,6 +132,18 @@ func LoadProjectBase() (*Project, error) {
 	if err != nil {
 		return nil, fmt.Errorf("loading project: %w", err)
 	}
+	err = project.readLockFile()
+	if err != nil {
+		return nil, fmt.Errorf("loading project: %w", err)
+	}
+	err = project.readProvidersLock()
+	if err != nil {
+		return nil, fmt.Errorf("loading project: %w", err)
+	}
+	err = project.readVendor()
+	if err != nil {
+		return nil, fmt.Errorf("loading project: %w", err)
+	}
 	err = project.readSecrets()
 	if err != nil {
 		return nil, fmt.Errorf("loading project: %w", err)
@@ -136,6 +177,10 @@ func LoadProjectFull() (*Project, error) {
 		}
 	}
 
+	err = project.applyEnvOverlays()
+	if err != nil {
+		return nil, fmt.Errorf("apply env overlays: %w", err)
+	}
 	err = project.prepareObjects()
 	if err != nil {
 		return nil, fmt.Errorf("prepare objects: %w", err)
@@ -156,6 +201,10 @@ func LoadProjectFull() (*Project, error) {
 	if err != nil {
 		return nil, fmt.Errorf("prepare units: %w", err)
 	}
+	err = project.saveLockFile()
+	if err != nil {
+		return nil, fmt.Errorf("save %v: %w", lockFileName, err)
+	}
 	return project, nil
 }
 
@@ -163,6 +212,7 @@ func LoadProjectFull() (*Project, error) {
 type ObjectData struct {
 	filename string
 	data     map[string]interface{}
+	rawData  []byte
 }
 
 func (p *Project) readObjects(objData []byte, filename string) error {
@@ -185,6 +235,7 @@ func (p *Project) readObjects(objData []byte, filename string) error {
 		p.objects[objKind] = append(p.objects[objKind], ObjectData{
 			filename: filename,
 			data:     obj,
+			rawData:  objData,
 		})
 	}
 	return nil
@@ -195,31 +246,60 @@ func (p *Project) prepareObjects() error {
 	if err != nil {
 		return err
 	}
+	err = p.readProjectReferences()
+	if err != nil {
+		return err
+	}
 	err = p.readStacks()
 	if err != nil {
 		return err
 	}
-	return nil
+	return p.resolveStackOutputs()
 }
 
 func (p *Project) readUnits() error {
-	// Read units from all stacks.
-	for stackName, stack := range p.Stacks {
-		for _, stackTmpl := range stack.Templates {
-			for _, unitData := range stackTmpl.Units {
-				unit, err := NewUnit(unitData, stack)
-				if err != nil {
-					traceUnitView, errYaml := yaml.Marshal(unitData)
-					if errYaml != nil {
-						traceUnitView = []byte{}
+	// Read units from all stacks and templates included into stacks.
+	for stackName, rootStack := range p.Stacks {
+		// hclNames maps converted names to unit names, e.g. 'a[b]' and 'a_b' can't be used in the same stack.
+		hclNames := map[string]string{}
+		for _, stack := range append([]*Stack{rootStack}, rootStack.includesTree()...) {
+			for _, stackTmpl := range stack.Templates {
+				for _, tmplUnitData := range stackTmpl.Units {
+					unitsData, err := expandUnitSpec(stack.namespacedUnitSpec(tmplUnitData), stack)
+					if err != nil {
+						return fmt.Errorf("stack '%v', reading units: %v", stackName, err.Error())
+					}
+					for _, unitData := range unitsData {
+						unitData, enabled, reason, err := unitEnabled(unitData, stack)
+						if err != nil {
+							return fmt.Errorf("stack '%v', reading units: %v", stackName, err.Error())
+						}
+						if !enabled {
+							unitKey := fmt.Sprintf("%v.%v", stackName, unitData["name"])
+							p.DisabledUnits[unitKey] = reason
+							log.Debugf("Unit '%v' is disabled (%v), ignore", unitKey, reason)
+							continue
+						}
+						unit, err := NewUnit(unitData, stack)
+						if err != nil {
+							traceUnitView, errYaml := yaml.Marshal(unitData)
+							if errYaml != nil {
+								traceUnitView = []byte{}
+							}
+							return fmt.Errorf("stack '%v', reading units: %v\nUnit data:\n%v", stackName, err.Error(), string(traceUnitView))
+						}
+						if _, exists := p.Units[unit.Key()]; exists {
+							return fmt.Errorf("stack '%v', reading units: duplicate unit name: %v", stackName, unit.Name())
+						}
+						hclName := ConvertToHCLName(unit.Name())
+						if other, exists := hclNames[hclName]; exists {
+							return fmt.Errorf("stack '%v', reading units: unit names '%v' and '%v' are both converted to '%v'", stackName, other, unit.Name(), hclName)
+						}
+						hclNames[hclName] = unit.Name()
+						p.Units[unit.Key()] = unit
+						log.Debugf("Unit added: '%v', tainted: %v", unit.Key(), unit.IsTainted())
 					}
-					return fmt.Errorf("stack '%v', reading units: %v\nUnit data:\n%v", stackName, err.Error(), string(traceUnitView))
-				}
-				if _, exists := p.Units[unit.Key()]; exists {
-					return fmt.Errorf("stack '%v', reading units: duplicate unit name: %v", stackName, unit.Name())
 				}
-				p.Units[unit.Key()] = unit
-				log.Debugf("Unit added: '%v', tainted: %v", unit.Key(), unit.IsTainted())
 			}
 		}
 	}
@@ -240,14 +320,42 @@ func (p *Project) prepareUnits() error {
 	return nil
 }
 
+// Validate runs static checks for all units which implement UnitValidator interface.
+func (p *Project) Validate() error {
+	keys := make([]string, 0, len(p.Units))
+	for key := range p.Units {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	errs := []string{}
+	for _, key := range keys {
+		validator, ok := p.Units[key].(UnitValidator)
+		if !ok {
+			continue
+		}
+		if err := validator.Validate(); err != nil {
+			errs = append(errs, fmt.Sprintf("unit '%v': %v", key, err.Error()))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("units validation failed:\n%v", strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
 func (p *Project) MkBuildDir() error {
 	baseOutDir := config.Global.WorkDir
 	if _, err := os.Stat(baseOutDir); os.IsNotExist(err) {
-		err := os.Mkdir(baseOutDir, 0755)
+		err := os.MkdirAll(baseOutDir, 0755)
 		if err != nil {
 			return err
 		}
 	}
+	if config.Global.TerraformCLIConfig != "" {
+		if err := writeTerraformCLIConfig(); err != nil {
+			return err
+		}
+	}
 	relPath, err := filepath.Rel(config.Global.WorkingDir, p.CodeCacheDir)
 	if err != nil {
 		return err
@@ -291,6 +399,12 @@ func (p *Project) Name() string {
 	return p.name
 }
 
+// ReadOnly returns true for referenced projects, which state is only read. Backends must not create anything
+// for them.
+func (p *Project) ReadOnly() bool {
+	return p.readOnly
+}
+
 // PrintInfo print project info.
 func (p *Project) PrintInfo() error {
 	fmt.Println("Project:")
@@ -340,6 +454,12 @@ func (p *Project) PrintInfo() error {
 				deps += "\n"
 			}
 		}
+		for _, path := range p.RemoteProjectDependencies(name) {
+			if deps != "" {
+				deps += "\n"
+			}
+			deps = fmt.Sprintf("%s%s %s", deps, remoteProjectOutputLinkType, path)
+		}
 		table.Append([]string{
 			name,
 			unit.Stack().Name,
@@ -16,22 +16,43 @@ import (
 const defaultProjectName = "default-project"
 const ignoreFileName = ".cdevignore"
 
+// envsDirName is the dir with environment overlays, selected by '--env' option.
+const envsDirName = "envs"
+
 func (p *Project) parseProjectConfig() error {
 
-	if p.configDataFile == nil {
+	if p.configDataFile == nil && p.envConfigDataFile == nil {
 		p.StateBackendName = "default"
 		p.name = defaultProjectName
+		if config.Global.Env != "" {
+			p.name = fmt.Sprintf("%v-%v", p.name, config.Global.Env)
+		}
 		return nil
 	}
-	var prjConfParsed map[string]interface{}
-	err := yaml.Unmarshal(p.configDataFile, &prjConfParsed)
-	if err != nil {
-		return fmt.Errorf("parsing project config: %v", utils.ResolveYamlError(p.configDataFile, err))
+	prjConfParsed := map[string]interface{}{}
+	if p.configDataFile != nil {
+		err := yaml.Unmarshal(p.configDataFile, &prjConfParsed)
+		if err != nil {
+			return fmt.Errorf("parsing project config: %v", utils.ResolveYamlError(p.configDataFile, err))
+		}
 	}
+	if p.envConfigDataFile != nil {
+		var envConfParsed map[string]interface{}
+		err := yaml.Unmarshal(p.envConfigDataFile, &envConfParsed)
+		if err != nil {
+			return fmt.Errorf("parsing env '%v' project config: %v", config.Global.Env, utils.ResolveYamlError(p.envConfigDataFile, err))
+		}
+		prjConfParsed = utils.DeepMergeMaps(prjConfParsed, envConfParsed)
+	}
+	var err error
 	if name, ok := prjConfParsed["name"].(string); !ok {
 		return fmt.Errorf("error in project config: name is required")
 	} else {
 		p.name = name
+		if config.Global.Env != "" {
+			// Environments share the project dir, so the name (used by backends for state keys) includes the env.
+			p.name = fmt.Sprintf("%v-%v", name, config.Global.Env)
+		}
 	}
 
 	if kn, ok := prjConfParsed["kind"].(string); !ok || kn != projectObjKindKey {
@@ -56,33 +77,32 @@ func (p *Project) parseProjectConfig() error {
 
 // Return project conf and slice of others config files.
 func (p *Project) readManifests() (err error) {
-	var files []string
-	files, _ = filepath.Glob(config.Global.WorkingDir + "/*.yaml")
-	filesYML, _ := filepath.Glob(config.Global.WorkingDir + "/*.yml")
-	files = append(files, filesYML...)
+	ignore := utils.ReadIgnoreFile(filepath.Join(config.Global.WorkingDir, ignoreFileName))
+	files, err := findManifests(config.Global.WorkingDir, ignore)
+	if err != nil {
+		return fmt.Errorf("reading configs: %w", err)
+	}
 	objFiles := make(map[string][]byte)
 
-	ignoreFileFullPath := filepath.Join(config.Global.WorkingDir, ignoreFileName)
-	ignoreData, _ := os.ReadFile(ignoreFileFullPath) // Ignore error, its ok
-	ignoreList := strings.Split(string(ignoreData), "\n")
-
-	ignoreFileCheck := func(filename string) bool {
-		for _, ignoreFile := range ignoreList {
-			if ignoreFile == filename {
-				return true
-			}
+	projectConfigFile := ""
+	if config.Global.ProjectConfig != "" {
+		projectConfigFile = config.Global.ProjectConfig
+		if !utils.IsAbsolutePath(projectConfigFile) {
+			projectConfigFile = filepath.Join(config.Global.WorkingDir, projectConfigFile)
+		}
+		p.configDataFile, err = os.ReadFile(projectConfigFile)
+		if err != nil {
+			return fmt.Errorf("reading project config: %v", err)
 		}
-		return false
 	}
 
 	for _, file := range files {
-		// log.Warnf("Read Files: %v, list: %v", file, ignoreList)
 		fileName, _ := filepath.Rel(config.Global.WorkingDir, file)
-		if ignoreFileCheck(fileName) {
-			log.Debugf("File ignored: %v", fileName)
+		isProjectConfig := regexp.MustCompile(ConfigFilePattern).MatchString(fileName)
+		if file == projectConfigFile || (isProjectConfig && projectConfigFile != "") {
+			// Project config is set by option, default config is skipped.
 			continue
 		}
-		isProjectConfig := regexp.MustCompile(ConfigFilePattern).MatchString(fileName)
 		if isProjectConfig {
 			p.configDataFile, err = os.ReadFile(file)
 		} else {
@@ -93,6 +113,172 @@ func (p *Project) readManifests() (err error) {
 		}
 	}
 	p.objectsFiles = objFiles
+	return p.readEnvManifests()
+}
+
+var (
+	// manifestKindRe matches kinds of project objects. Other yaml files in nested dirs (helm charts, kubernetes
+	// manifests, variables) are not project objects.
+	manifestKindRe = regexp.MustCompile(fmt.Sprintf(`(?m)^kind:\s*["']?(%s|%s|%s)["']?\s*$`,
+		stackObjKindKey, backendObjKindKey, projectReferenceObjKindKey))
+	// Kubernetes secrets have the same kind as project secrets, only secrets with the driver are project objects.
+	secretKindRe        = regexp.MustCompile(fmt.Sprintf(`(?m)^kind:\s*["']?%s["']?\s*$`, secretObjKindKey))
+	secretDriverRe      = regexp.MustCompile(`(?m)^driver:`)
+	yamlDocSeparatorRe  = regexp.MustCompile(`(?m)^---`)
+	stackTemplateKindRe = regexp.MustCompile(`(?m)^kind:\s*["']?StackTemplate["']?\s*$`)
+)
+
+// findManifests returns yaml files of the project. Files in the project dir are always read. Nested dirs are searched
+// recursively for files with project objects (stacks, backends, project references and secrets), except hidden dirs,
+// env overlays dir, vendor dir and stack template dirs (dirs with StackTemplate files). Files and dirs matched by
+// .cdevignore patterns are skipped.
+func findManifests(projectDir string, ignore *utils.IgnoreMatcher) ([]string, error) {
+	files := []string{}
+	err := filepath.WalkDir(projectDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, _ := filepath.Rel(projectDir, path)
+		if relPath == "." {
+			return nil
+		}
+		if d.IsDir() {
+			if strings.HasPrefix(d.Name(), ".") || relPath == envsDirName || relPath == vendorDirName || ignore.Match(relPath, true) {
+				log.Debugf("Dir skipped: %v", relPath)
+				return filepath.SkipDir
+			}
+			if isStackTemplateDir(path) {
+				log.Debugf("Stack template dir skipped: %v", relPath)
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !isYAMLFile(path) {
+			return nil
+		}
+		if ignore.Match(relPath, false) {
+			log.Debugf("File ignored: %v", relPath)
+			return nil
+		}
+		if filepath.Dir(relPath) != "." {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			if !isProjectManifest(data) {
+				log.Debugf("File skipped, not a project object: %v", relPath)
+				return nil
+			}
+		}
+		files = append(files, path)
+		return nil
+	})
+	return files, err
+}
+
+// isProjectManifest checks if the yaml data contains project objects.
+func isProjectManifest(data []byte) bool {
+	for _, doc := range yamlDocSeparatorRe.Split(string(data), -1) {
+		if manifestKindRe.MatchString(doc) || (secretKindRe.MatchString(doc) && secretDriverRe.MatchString(doc)) {
+			return true
+		}
+	}
+	return false
+}
+
+func isYAMLFile(path string) bool {
+	ext := filepath.Ext(path)
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// isStackTemplateDir checks if the dir contains stack template files.
+func isStackTemplateDir(dir string) bool {
+	files, _ := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	for _, fn := range files {
+		data, err := os.ReadFile(fn)
+		if err == nil && stackTemplateKindRe.Match(data) {
+			return true
+		}
+	}
+	return false
+}
+
+// readEnvManifests reads files of the environment overlay selected by '--env' option: project config,
+// which is merged with the main one, and objects files. Objects with the same kind and name as main objects
+// are merged with them (see applyEnvOverlays), others are added to the project.
+func (p *Project) readEnvManifests() error {
+	if config.Global.Env == "" {
+		return nil
+	}
+	envDir := filepath.Join(config.Global.WorkingDir, envsDirName, config.Global.Env)
+	isDir, err := utils.CheckDir(envDir)
+	if err != nil || !isDir {
+		return fmt.Errorf("env '%v': dir '%v' not found", config.Global.Env, filepath.Join(envsDirName, config.Global.Env))
+	}
+	files, _ := filepath.Glob(envDir + "/*.yaml")
+	filesYML, _ := filepath.Glob(envDir + "/*.yml")
+	files = append(files, filesYML...)
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("reading env configs %v: %v", file, err)
+		}
+		if regexp.MustCompile(ConfigFilePattern).MatchString(filepath.Base(file)) {
+			p.envConfigDataFile = data
+			continue
+		}
+		p.objectsFiles[file] = data
+		p.envFiles[file] = true
+	}
+	return nil
+}
+
+// applyEnvOverlays deep-merges objects from environment files into the main objects with the same kind and name.
+func (p *Project) applyEnvOverlays() error {
+	if len(p.envFiles) == 0 {
+		return nil
+	}
+	for kind, objs := range p.objects {
+		res := []ObjectData{}
+		mainObjs := map[string]int{}
+		for _, obj := range objs {
+			if p.envFiles[obj.filename] {
+				continue
+			}
+			if name, ok := obj.data["name"].(string); ok {
+				mainObjs[name] = len(res)
+			}
+			res = append(res, obj)
+		}
+		for _, obj := range objs {
+			if !p.envFiles[obj.filename] {
+				continue
+			}
+			name, _ := obj.data["name"].(string)
+			i, exists := mainObjs[name]
+			if !exists {
+				res = append(res, obj)
+				continue
+			}
+			log.Debugf("Env '%v': %v '%v' is merged with overlay from %v", config.Global.Env, kind, name, obj.filename)
+			overlay := obj.data
+			if vars, ok := overlay["variables"].(map[string]interface{}); ok && kind == stackObjKindKey {
+				// Stack variables are merged later to keep the source of values (see readStackVariables).
+				p.variablesOverrides[name] = append(p.variablesOverrides[name], stackVariablesOverride{
+					source:    obj.filename,
+					variables: vars,
+				})
+				overlay = make(map[string]interface{}, len(obj.data))
+				for k, v := range obj.data {
+					if k != "variables" {
+						overlay[k] = v
+					}
+				}
+			}
+			res[i].data = utils.DeepMergeMaps(res[i].data, overlay)
+		}
+		p.objects[kind] = res
+	}
 	return nil
 }
 
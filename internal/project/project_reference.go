@@ -0,0 +1,246 @@
+package project
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/apex/log"
+	"github.com/olekukonko/tablewriter"
+	"github.com/shalb/cluster.dev/pkg/utils"
+)
+
+const projectReferenceObjKindKey = "ProjectReference"
+const remoteProjectOutputLinkType = "remoteProjectOutput"
+
+// ProjectReference describes another cdev project, which outputs are used in this project.
+// The state of the referenced project is read only and never locked.
+type ProjectReference struct {
+	Name        string
+	ProjectName string
+	Backend     Backend
+	state       *stateData
+}
+
+func (p *Project) readProjectReferences() error {
+	refs, exists := p.objects[projectReferenceObjKindKey]
+	if !exists {
+		return nil
+	}
+	for _, obj := range refs {
+		ref, err := p.readProjectReferenceObj(obj)
+		if err != nil {
+			return fmt.Errorf("reading project reference: %w", err)
+		}
+		if _, exists := p.ProjectReferences[ref.Name]; exists {
+			return fmt.Errorf("reading project reference: duplicate name '%v'", ref.Name)
+		}
+		p.ProjectReferences[ref.Name] = ref
+		log.Debugf("Project reference added: %v (project '%v')", ref.Name, ref.ProjectName)
+	}
+	return nil
+}
+
+func (p *Project) readProjectReferenceObj(obj ObjectData) (*ProjectReference, error) {
+	name, ok := obj.data["name"].(string)
+	if !ok {
+		return nil, fmt.Errorf("config must contain field 'name'")
+	}
+	ref := &ProjectReference{
+		Name:        name,
+		ProjectName: name,
+	}
+	if projectName, exists := obj.data["project"]; exists {
+		ref.ProjectName, ok = projectName.(string)
+		if !ok {
+			return nil, fmt.Errorf("'%v': field 'project' should be string, not %T", name, projectName)
+		}
+	}
+	provider, spec, err := p.projectReferenceBackendSpec(obj)
+	if err != nil {
+		return nil, fmt.Errorf("'%v': %w", name, err)
+	}
+	if provider == "local" {
+		// Local backend without path is the state dir of this project.
+		specMap, _ := spec.(map[string]interface{})
+		if path, _ := specMap["path"].(string); path == "" {
+			return nil, fmt.Errorf("'%v': local backend requires 'path' to the state dir of the referenced project", name)
+		}
+	}
+	// Backends use project name to build the state key, so the backend is created for the referenced project.
+	ref.Backend, err = newBackend(name, provider, spec, &Project{name: ref.ProjectName, readOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("'%v': %w", name, err)
+	}
+	return ref, nil
+}
+
+// projectReferenceBackendSpec returns backend provider and spec of the referenced project. It can be set with
+// 'provider' and 'spec' fields, or with 'backend' field - the name of the backend declared in this project.
+func (p *Project) projectReferenceBackendSpec(obj ObjectData) (provider string, spec interface{}, err error) {
+	backendName, hasBackend := obj.data["backend"].(string)
+	provider, hasProvider := obj.data["provider"].(string)
+	if hasBackend == hasProvider {
+		return "", nil, fmt.Errorf("one of fields 'backend' or 'provider' is required")
+	}
+	if hasProvider {
+		spec, ok := obj.data["spec"]
+		if !ok {
+			return "", nil, fmt.Errorf("config must contain field 'spec'")
+		}
+		return provider, spec, nil
+	}
+	if backendName == "default" {
+		return "", nil, fmt.Errorf("backend 'default' is the local state of this project, set the backend of the referenced project")
+	}
+	for _, bk := range p.objects[backendObjKindKey] {
+		if bk.data["name"] != backendName {
+			continue
+		}
+		provider, ok := bk.data["provider"].(string)
+		if !ok {
+			return "", nil, fmt.Errorf("backend '%v' must contain field 'provider'", backendName)
+		}
+		return provider, bk.data["spec"], nil
+	}
+	return "", nil, fmt.Errorf("backend '%v' not found", backendName)
+}
+
+// readState reads the state of the referenced project once, without locking.
+func (r *ProjectReference) readState() (*stateData, error) {
+	if r.state != nil {
+		return r.state, nil
+	}
+	stateStr, err := r.Backend.ReadState()
+	if err != nil {
+		return nil, fmt.Errorf("read state of project '%v': %w", r.ProjectName, err)
+	}
+	if stateStr == "" {
+		return nil, fmt.Errorf("read state of project '%v': state is empty", r.ProjectName)
+	}
+	st := stateData{
+		UnitLinks: &UnitLinksT{},
+	}
+	if err = utils.JSONDecode([]byte(stateStr), &st); err != nil {
+		return nil, fmt.Errorf("read state of project '%v': %w", r.ProjectName, err)
+	}
+	r.state = &st
+	return r.state, nil
+}
+
+// Output returns the output of the referenced project. Path is 'stack.unit.output' for unit outputs
+// or 'stack.output' for stack outputs.
+func (r *ProjectReference) Output(path string) (interface{}, error) {
+	st, err := r.readState()
+	if err != nil {
+		return nil, err
+	}
+	splittedPath := strings.Split(path, ".")
+	switch len(splittedPath) {
+	case 2:
+		if val, exists := st.StackOutputs[splittedPath[0]][splittedPath[1]]; exists {
+			return val, nil
+		}
+		return nil, fmt.Errorf("project '%v': stack output '%v' not found in state", r.ProjectName, path)
+	case 3:
+		for _, link := range st.UnitLinks.ByLinkTypes(OutputLinkType).Map() {
+			if link.TargetStackName == splittedPath[0] && link.TargetUnitName == splittedPath[1] && link.OutputName == splittedPath[2] && link.OutputData != nil {
+				return link.OutputData, nil
+			}
+		}
+		return nil, fmt.Errorf("project '%v': output '%v' not found in state, the output should be used in the project to be saved in the state", r.ProjectName, path)
+	}
+	return nil, fmt.Errorf("bad output path '%v', expected 'stack.unit.output' or 'stack.output'", path)
+}
+
+// RemoteProjectOutput describes the output of referenced project used in this project.
+type RemoteProjectOutput struct {
+	Value  interface{}
+	UsedBy []string
+}
+
+// remoteProjectOutput template function, creates the marker for the output of the referenced project.
+// Path format: 'reference_name.stack.unit.output' or 'reference_name.stack.output'.
+// Markers are replaced with values after all objects are read, so the function can be used in any project object.
+func (p *Project) remoteProjectOutput(path string) (string, error) {
+	if len(strings.Split(path, ".")) < 3 {
+		return "", fmt.Errorf("remoteProjectOutput tmpl: bad path '%v', expected 'project.stack.unit.output' or 'project.stack.output'", path)
+	}
+	markerPath := fmt.Sprintf("%v.%v", remoteProjectOutputLinkType, path)
+	hash := utils.Md5(markerPath)
+	marker, err := EscapeForMarkerStr(fmt.Sprintf("%s.%s.%s", hash, markerPath, hash))
+	if err != nil {
+		return "", err
+	}
+	p.remoteProjectMarkers[marker] = path
+	return marker, nil
+}
+
+// remoteProjectOutputValue returns the value of referenced project output.
+func (p *Project) remoteProjectOutputValue(path string) (interface{}, error) {
+	if out, exists := p.RemoteProjectOutputs[path]; exists {
+		return out.Value, nil
+	}
+	splittedPath := strings.SplitN(path, ".", 2)
+	ref, exists := p.ProjectReferences[splittedPath[0]]
+	if !exists {
+		return nil, fmt.Errorf("remoteProjectOutput '%v': project reference '%v' not found", path, splittedPath[0])
+	}
+	val, err := ref.Output(splittedPath[1])
+	if err != nil {
+		return nil, fmt.Errorf("remoteProjectOutput '%v': %w", path, err)
+	}
+	p.RemoteProjectOutputs[path] = &RemoteProjectOutput{Value: val}
+	return val, nil
+}
+
+// RemoteProjectDependencies returns sorted paths of referenced projects outputs used by the unit.
+func (p *Project) RemoteProjectDependencies(unitKey string) []string {
+	res := []string{}
+	for path, out := range p.RemoteProjectOutputs {
+		for _, usedBy := range out.UsedBy {
+			// Outputs are resolved before 'for_each' and 'count' expansion, so instances use outputs of the unit.
+			if usedBy == unitKey || strings.HasPrefix(unitKey, usedBy+"[") {
+				res = append(res, path)
+				break
+			}
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+// printRemoteProjectOutputs prints outputs of referenced projects used in this project.
+func (p *Project) printRemoteProjectOutputs() {
+	if len(p.RemoteProjectOutputs) == 0 {
+		return
+	}
+	paths := make([]string, 0, len(p.RemoteProjectOutputs))
+	for path := range p.RemoteProjectOutputs {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	fmt.Println("Remote project outputs:")
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Output", "Value", "Used by"})
+	for _, path := range paths {
+		out := p.RemoteProjectOutputs[path]
+		val, ok := out.Value.(string)
+		if !ok {
+			val, _ = InsertYaml(out.Value)
+			val = strings.TrimSpace(val)
+		}
+		usedBy := map[string]bool{}
+		units := []string{}
+		for _, u := range out.UsedBy {
+			if !usedBy[u] {
+				usedBy[u] = true
+				units = append(units, u)
+			}
+		}
+		sort.Strings(units)
+		table.Append([]string{path, val, strings.Join(units, "\n")})
+	}
+	table.Render()
+}
@@ -0,0 +1,209 @@
+package project
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"github.com/apex/log"
+	"github.com/olekukonko/tablewriter"
+	"github.com/shalb/cluster.dev/internal/config"
+	"github.com/shalb/cluster.dev/pkg/hcltools"
+)
+
+// ProvidersLockFileName is the project-wide terraform dependency lock file, created by 'cdev providers lock'.
+const ProvidersLockFileName = ".terraform.lock.hcl"
+
+const providersLockHeader = "# This file is maintained by 'cdev providers lock', do not edit it manually.\n# It is copied to each terraform-based unit.\n"
+
+// UnitProvidersLocker is an optional interface for units which use terraform providers (used by
+// 'cdev providers lock'). LockProviders initializes the built unit and returns providers locked by terraform.
+type UnitProvidersLocker interface {
+	LockProviders(upgrade bool) (map[string]hcltools.LockedProvider, error)
+}
+
+// unitLockedProvider is the provider locked by the unit.
+type unitLockedProvider struct {
+	unitKey string
+	hcltools.LockedProvider
+}
+
+// readProvidersLock reads the project-wide lock file. Missing file gives an empty lock.
+func (p *Project) readProvidersLock() error {
+	data, err := os.ReadFile(filepath.Join(config.Global.WorkingDir, ProvidersLockFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("reading %v: %w", ProvidersLockFileName, err)
+	}
+	if _, err = hcltools.ParseLockFile(data); err != nil {
+		return fmt.Errorf("reading %v: %w", ProvidersLockFileName, err)
+	}
+	p.providersLock = data
+	return nil
+}
+
+// ProvidersLock returns the content of the project-wide lock file to be copied to units. Returns nil if the project
+// has no lock file.
+func (p *Project) ProvidersLock() []byte {
+	return p.providersLock
+}
+
+// LockProviders initializes all terraform-based units and writes the project-wide lock file with one version of each
+// provider used by any unit. The newest version locked by units, which matches constraints of all units, is
+// selected. Units locked at other versions are reported. With upgrade the current project lock is ignored.
+func (p *Project) LockProviders(upgrade bool) error {
+	if err := p.ClearCacheDir(); err != nil {
+		return fmt.Errorf("lock providers: %w", err)
+	}
+	if upgrade {
+		p.providersLock = nil
+	}
+	keys := make([]string, 0, len(p.Units))
+	for key := range p.Units {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	byAddress := map[string][]unitLockedProvider{}
+	for _, key := range keys {
+		unit := p.Units[key]
+		locker, ok := unit.(UnitProvidersLocker)
+		if !ok {
+			continue
+		}
+		if err := unit.Build(); err != nil {
+			return fmt.Errorf("lock providers: build unit '%v': %w", key, err)
+		}
+		log.Infof("Unit '%v': locking providers...", key)
+		providers, err := locker.LockProviders(upgrade)
+		if err != nil {
+			return fmt.Errorf("lock providers: unit '%v': %w", key, err)
+		}
+		for address, prov := range providers {
+			byAddress[address] = append(byAddress[address], unitLockedProvider{unitKey: key, LockedProvider: prov})
+		}
+	}
+	addresses := make([]string, 0, len(byAddress))
+	for address := range byAddress {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+	res := map[string]hcltools.LockedProvider{}
+	conflicts := []string{}
+	for _, address := range addresses {
+		locked := byAddress[address]
+		prov, err := mergeLockedProvider(address, locked)
+		if err != nil {
+			conflicts = append(conflicts, err.Error())
+			continue
+		}
+		res[address] = prov
+		for _, l := range locked {
+			if l.Version != prov.Version {
+				log.Warnf("Unit '%v': provider '%v' version %v differs from the project lock, %v is used", l.unitKey, address, l.Version, prov.Version)
+			}
+		}
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("lock providers: units require incompatible versions:\n%v", strings.Join(conflicts, "\n"))
+	}
+	data := hcltools.FormatLockFile(providersLockHeader, res)
+	if err := os.WriteFile(filepath.Join(config.Global.WorkingDir, ProvidersLockFileName), data, 0644); err != nil {
+		return fmt.Errorf("lock providers: %w", err)
+	}
+	p.providersLock = data
+	p.printLockedProviders(res, byAddress)
+	return nil
+}
+
+// printLockedProviders prints the project lock with units which use each provider.
+func (p *Project) printLockedProviders(providers map[string]hcltools.LockedProvider, byAddress map[string][]unitLockedProvider) {
+	addresses := make([]string, 0, len(providers))
+	for address := range providers {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Provider", "Version", "Constraints", "Units"})
+	for _, address := range addresses {
+		units := []string{}
+		for _, l := range byAddress[address] {
+			units = append(units, l.unitKey)
+		}
+		table.Append([]string{address, providers[address].Version, providers[address].Constraints, strings.Join(units, "\n")})
+	}
+	table.Render()
+}
+
+// mergeLockedProvider selects the newest version locked by units which matches constraints of all units. Checksums
+// of the version are merged.
+func mergeLockedProvider(address string, locked []unitLockedProvider) (hcltools.LockedProvider, error) {
+	res := hcltools.LockedProvider{Address: address}
+	constraints := []*semver.Constraints{}
+	constraintStrs := map[string]bool{}
+	versions := []*semver.Version{}
+	for _, l := range locked {
+		ver, err := semver.NewVersion(l.Version)
+		if err != nil {
+			return res, fmt.Errorf("  %v: unit '%v': parse version '%v': %v", address, l.unitKey, l.Version, err)
+		}
+		versions = append(versions, ver)
+		if l.Constraints == "" {
+			continue
+		}
+		constraint, err := hcltools.ParseVersionConstraint(l.Constraints)
+		if err != nil {
+			return res, fmt.Errorf("  %v: unit '%v': %v", address, l.unitKey, err)
+		}
+		constraints = append(constraints, constraint)
+		for _, c := range strings.Split(l.Constraints, ",") {
+			constraintStrs[strings.TrimSpace(c)] = true
+		}
+	}
+	sort.Sort(sort.Reverse(semver.Collection(versions)))
+	for _, ver := range versions {
+		matches := true
+		for _, constraint := range constraints {
+			if !constraint.Check(ver) {
+				matches = false
+				break
+			}
+		}
+		if !matches {
+			continue
+		}
+		res.Version = ver.Original()
+		break
+	}
+	if res.Version == "" {
+		details := []string{}
+		for _, l := range locked {
+			details = append(details, fmt.Sprintf("unit '%v': %v (constraints '%v')", l.unitKey, l.Version, l.Constraints))
+		}
+		return res, fmt.Errorf("  %v: no locked version matches constraints of all units: %v", address, strings.Join(details, ", "))
+	}
+	constraintsList := make([]string, 0, len(constraintStrs))
+	for c := range constraintStrs {
+		constraintsList = append(constraintsList, c)
+	}
+	sort.Strings(constraintsList)
+	res.Constraints = strings.Join(constraintsList, ", ")
+	hashes := map[string]bool{}
+	for _, l := range locked {
+		if l.Version != res.Version {
+			continue
+		}
+		for _, hash := range l.Hashes {
+			hashes[hash] = true
+		}
+	}
+	for hash := range hashes {
+		res.Hashes = append(res.Hashes, hash)
+	}
+	sort.Strings(res.Hashes)
+	return res, nil
+}
@@ -26,6 +26,9 @@ func OutputsScanner(data reflect.Value, unit Unit) (reflect.Value, error) {
 			modKey := fmt.Sprintf("%s.%s", link.TargetStackName, link.TargetUnitName)
 			depUnit, exists := unit.Project().Units[modKey]
 			if !exists {
+				if err := unit.Project().CheckUnitDisabled(modKey); err != nil {
+					return reflect.ValueOf(nil), fmt.Errorf("unit '%s.%s' refers to output of disabled unit: %w", unit.Stack().Name, unit.Name(), err)
+				}
 				return reflect.ValueOf(nil), fmt.Errorf("depend unit does not exists. Src: '%s.%s', depend: '%s'", unit.Stack().Name, unit.Name(), modKey)
 			}
 			// Add unit ptr to unit link.
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/apex/log"
@@ -23,7 +24,13 @@ type Stack struct {
 	TemplateDir string
 	Templates   []stackTemplate
 	Variables   map[string]interface{}
-	ConfigData  map[string]interface{}
+	// VariablesSources contains sources (files, options) of variables values by dot separated paths.
+	VariablesSources map[string]string
+	ConfigData       map[string]interface{}
+	// UnitsPrefix is the namespace of units of included template ('include_name:'), empty for the stack itself.
+	UnitsPrefix string
+	Includes    []*Stack
+	Outputs     map[string]interface{}
 }
 
 func (p *Project) readStacks() error {
@@ -38,6 +45,14 @@ func (p *Project) readStacks() error {
 		}
 		log.Warnf("'Infrastructure' key is deprecated and will be removed in future releases. Use 'Stack' instead")
 	}
+	err := p.readVariablesOverrides()
+	if err != nil {
+		return err
+	}
+	err = p.checkVariablesOverrides()
+	if err != nil {
+		return err
+	}
 	for _, stack := range stacks {
 		err := p.readStackObj(stack)
 		if err != nil {
@@ -87,11 +102,23 @@ func (p *Project) readStackObj(stackSpec ObjectData) error {
 	if !ok {
 		return fmt.Errorf("stack object must contain field 'template'")
 	}
-	stack.Variables, ok = stackSpec.data["variables"].(map[string]interface{})
-	if !ok {
-		return fmt.Errorf("stack object must contain field 'variables'")
+	err := stack.readStackVariables(stackSpec)
+	if err != nil {
+		return fmt.Errorf("stack '%v': %w", name, err)
+	}
+	err = stack.ReadTemplate(tmplSource)
+	if err != nil {
+		return err
+	}
+	err = stack.applyVariablesSchema(stackSpec)
+	if err != nil {
+		return err
+	}
+	err = stack.readIncludes(nil)
+	if err != nil {
+		return err
 	}
-	err := stack.ReadTemplate(tmplSource)
+	err = stack.readStackOutputs()
 	if err != nil {
 		return err
 	}
@@ -106,6 +133,10 @@ func (p *Project) readStackObj(stackSpec ObjectData) error {
 		return fmt.Errorf("backend '%s' not found, stack: '%s'", stack.BackendName, stack.Name)
 	}
 	stack.Backend = bPtr
+	for _, inc := range stack.includesTree() {
+		inc.BackendName = stack.BackendName
+		inc.Backend = bPtr
+	}
 	p.Stacks[name] = &stack
 	log.Debugf("Stack added: %v", name)
 	return nil
@@ -114,68 +145,78 @@ func (p *Project) readStackObj(stackSpec ObjectData) error {
 // ReadTemplate read all templates in src.
 func (s *Stack) ReadTemplate(src string) (err error) {
 	// Read stack template data and apply variables.
-	var templatesDir string
+	s.TemplateDir, err = s.ProjectPtr.resolveTemplateDir(src, config.Global.WorkingDir)
+	if err != nil {
+		return err
+	}
+	s.TemplateSrc = src
+	return s.readTemplateFiles()
+}
+
+// resolveTemplateDir returns the path to the template dir relative to project dir.
+// Local relative source is resolved from baseDir, git, archive and OCI sources are downloaded to templates cache dir.
+func (p *Project) resolveTemplateDir(src, baseDir string) (string, error) {
 	if utils.IsLocalPath(src) {
+		var templatesDir string
 		if utils.IsAbsolutePath(src) {
 			templatesDir = src
 		} else {
-			templatesDir = filepath.Join(config.Global.WorkingDir, src)
+			templatesDir = filepath.Join(baseDir, src)
 		}
 		isDir, err := utils.CheckDir(templatesDir)
 		if err != nil {
-			return err
+			return "", err
 		}
 		if !isDir {
-			return fmt.Errorf("reading templates: local source should be a dir")
+			return "", fmt.Errorf("reading templates: local source should be a dir")
 		}
 		log.Debugf("Template dir: %v", templatesDir)
-		s.TemplateDir, err = filepath.Rel(config.Global.WorkingDir, templatesDir)
+		relDir, err := filepath.Rel(config.Global.WorkingDir, templatesDir)
 		if err != nil {
-			s.TemplateDir = templatesDir
+			return templatesDir, nil
 		}
-	} else {
-		os.Mkdir(config.Global.TemplatesCacheDir, os.ModePerm)
-		parsedRepoURL, err := utils.ParseGitUrl(src)
-		if err != nil {
-			return fmt.Errorf("download template: %w", err)
-		}
-		folderName, err := utils.URLToFolderName(parsedRepoURL.RepoString)
-		if err != nil {
-			return fmt.Errorf("download template: %w", err)
+		return relDir, nil
+	}
+	dr, vendored, err := p.VendoredSource(src)
+	if err != nil {
+		return "", err
+	}
+	if !vendored {
+		if archive, isArchive := utils.ParseArchiveSource(src); isArchive {
+			dr, err = p.getLockedArchiveSource(src, archive, lockedTemplateKind)
+		} else if utils.IsOCIReference(src) {
+			dr, err = p.getLockedOCISource(src, lockedTemplateKind)
+		} else {
+			dr, err = p.getLockedGitSource(src, src, lockedTemplateKind)
 		}
-		dr, err := utils.GetTemplate(src, config.Global.TemplatesCacheDir, folderName)
 		if err != nil {
-			return fmt.Errorf("download template: %w\n   See details about stack template reference: https://docs.cluster.dev/structure-stack/", err)
+			return "", fmt.Errorf("download template: %w\n   See details about stack template reference: https://docs.cluster.dev/structure-stack/", err)
 		}
-		log.Debugf("Template dir: %v", dr)
-		s.TemplateDir, err = filepath.Rel(config.Global.WorkingDir, dr)
-		if err != nil {
-			return fmt.Errorf("reading templates: error parsing tmpl dir: %w", err)
+		if err = p.addVendorDir(src, lockedTemplateKind, dr); err != nil {
+			return "", err
 		}
 	}
-
-	templatesFilesList, err := filepath.Glob(s.TemplateDir + "/*.yaml")
+	log.Debugf("Template dir: %v", dr)
+	relDir, err := filepath.Rel(config.Global.WorkingDir, dr)
 	if err != nil {
-		return err
+		return "", fmt.Errorf("reading templates: error parsing tmpl dir: %w", err)
 	}
-	ignoreFileFullPath := filepath.Join(s.TemplateDir, ignoreFileName)
-	ignoreData, _ := os.ReadFile(ignoreFileFullPath) // Ignore error, its ok
-	ignoreList := strings.Split(string(ignoreData), "\n")
+	return relDir, nil
+}
 
-	ignoreFileCheck := func(filename string) bool {
-		for _, ignoreFile := range ignoreList {
-			if ignoreFile == filename {
-				return true
-			}
-		}
-		return false
+// readTemplateFiles reads and renders all template files in stack template dir.
+func (s *Stack) readTemplateFiles() error {
+	ignore := utils.ReadIgnoreFile(filepath.Join(s.TemplateDir, ignoreFileName))
+	templatesFilesList, err := findTemplateFiles(s.TemplateDir, ignore)
+	if err != nil {
+		return err
 	}
 	s.Templates = []stackTemplate{}
+	// Local sources of includes, templates from these dirs are read as separate included templates.
+	includeDirs := []string{}
 	for _, fn := range templatesFilesList {
-		// if ignoreFileCheck(fn)
-		checkFileName, _ := filepath.Rel(s.TemplateDir, fn)
-		if ignoreFileCheck(checkFileName) {
-			log.Debugf("Ignore stackTemplate file: %v", fn)
+		if inDirs(fn, includeDirs) {
+			log.Debugf("Skip stackTemplate file of included template: %v", fn)
 			continue
 		}
 		tmplData, err := os.ReadFile(fn)
@@ -195,11 +236,15 @@ func (s *Stack) ReadTemplate(src string) (err error) {
 			return err
 		}
 		s.Templates = append(s.Templates, *stackTemplate)
+		for _, inc := range stackTemplate.Includes {
+			if utils.IsLocalPath(inc.Source) && !utils.IsAbsolutePath(inc.Source) {
+				includeDirs = append(includeDirs, filepath.Join(s.TemplateDir, inc.Source))
+			}
+		}
 	}
 	if len(s.Templates) < 1 {
 		return fmt.Errorf("reading templates: no templates found")
 	}
-	s.TemplateSrc = src
 	return nil
 }
 
@@ -214,3 +259,58 @@ func (i *Stack) TemplateMust(data []byte, fileName string) (res []byte, err erro
 func (i *Stack) TemplateTry(data []byte, fileName string) (res []byte, warn bool, err error) {
 	return templateTry(data, i.ConfigData, i.ProjectPtr, i, fileName)
 }
+
+// findTemplateFiles returns template files of the stack template dir. Files in nested dirs are read only if they
+// contain StackTemplate objects, so nested dirs can store other files (manifests, values, etc.).
+// Files and dirs matched by .cdevignore patterns and the tests dir are skipped.
+func findTemplateFiles(templateDir string, ignore *utils.IgnoreMatcher) ([]string, error) {
+	files := []string{}
+	err := filepath.WalkDir(templateDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, _ := filepath.Rel(templateDir, path)
+		if relPath == "." {
+			return nil
+		}
+		if d.IsDir() {
+			if strings.HasPrefix(d.Name(), ".") || relPath == templateTestsDirName || ignore.Match(relPath, true) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) != ".yaml" {
+			return nil
+		}
+		if ignore.Match(relPath, false) {
+			log.Debugf("Ignore stackTemplate file: %v", path)
+			return nil
+		}
+		if filepath.Dir(relPath) != "." {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			if !stackTemplateKindRe.Match(data) {
+				return nil
+			}
+		}
+		files = append(files, path)
+		return nil
+	})
+	// Files of upper dirs first, to find includes before reading nested dirs.
+	sort.SliceStable(files, func(i, j int) bool {
+		return strings.Count(files[i], string(filepath.Separator)) < strings.Count(files[j], string(filepath.Separator))
+	})
+	return files, err
+}
+
+// inDirs checks if the file is located in one of dirs.
+func inDirs(fn string, dirs []string) bool {
+	for _, dir := range dirs {
+		if rel, err := filepath.Rel(dir, fn); err == nil && !strings.HasPrefix(rel, "..") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+package project
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/apex/log"
+	"github.com/shalb/cluster.dev/internal/config"
+)
+
+// unitsNamespaceSeparator separates the include name and the unit name in namespaced unit names ('addons:ingress').
+const unitsNamespaceSeparator = ":"
+
+var includeNameRe = regexp.MustCompile(`^[a-zA-Z0-9_\-]+$`)
+
+// templateInclude describes the stack template included into another stack template.
+type templateInclude struct {
+	Name      string                 `yaml:"name"`
+	Source    string                 `yaml:"source"`
+	Variables map[string]interface{} `yaml:"variables,omitempty"`
+}
+
+// readIncludes reads all templates included by the stack templates. Each include is represented by the nested stack
+// with the same name and backend, own template dir and variables, and the namespace for units.
+// chain is the list of template dirs of parents, used to detect include loops.
+func (s *Stack) readIncludes(chain []string) error {
+	chain = append(chain, s.TemplateDir)
+	s.Includes = []*Stack{}
+	names := map[string]bool{}
+	for _, tmpl := range s.Templates {
+		for _, inc := range tmpl.Includes {
+			if !includeNameRe.MatchString(inc.Name) {
+				return fmt.Errorf("stack '%v', template '%v': bad include name '%v', only letters, digits, '_' and '-' are allowed", s.Name, tmpl.Name, inc.Name)
+			}
+			if names[inc.Name] {
+				return fmt.Errorf("stack '%v', template '%v': duplicate include name '%v'", s.Name, tmpl.Name, inc.Name)
+			}
+			names[inc.Name] = true
+			if inc.Source == "" {
+				return fmt.Errorf("stack '%v', template '%v': include '%v': field 'source' is required", s.Name, tmpl.Name, inc.Name)
+			}
+			child, err := s.newInclude(inc, chain)
+			if err != nil {
+				return fmt.Errorf("stack '%v', template '%v': include '%v': %w", s.Name, tmpl.Name, inc.Name, err)
+			}
+			s.Includes = append(s.Includes, child)
+		}
+	}
+	return nil
+}
+
+func (s *Stack) newInclude(inc templateInclude, chain []string) (*Stack, error) {
+	templateDir, err := s.ProjectPtr.resolveTemplateDir(inc.Source, filepath.Join(config.Global.WorkingDir, s.TemplateDir))
+	if err != nil {
+		return nil, err
+	}
+	for _, dir := range chain {
+		if dir == templateDir {
+			return nil, fmt.Errorf("include loop detected, template dir '%v' is already included", templateDir)
+		}
+	}
+	variables := inc.Variables
+	if variables == nil {
+		variables = map[string]interface{}{}
+	}
+	configData := make(map[string]interface{}, len(s.ConfigData))
+	for k, v := range s.ConfigData {
+		configData[k] = v
+	}
+	configData["variables"] = variables
+	child := &Stack{
+		ProjectPtr:  s.ProjectPtr,
+		Name:        s.Name,
+		TemplateSrc: inc.Source,
+		TemplateDir: templateDir,
+		Variables:   variables,
+		ConfigData:  configData,
+		UnitsPrefix: s.UnitsPrefix + inc.Name + unitsNamespaceSeparator,
+	}
+	log.Debugf("Stack '%v': reading included template '%v' from '%v'", s.Name, inc.Name, templateDir)
+	if err = child.readTemplateFiles(); err != nil {
+		return nil, err
+	}
+	// Variables of the include are set in the parent template, so errors refer to the parent template dir.
+	if err = child.applyVariablesSchema(ObjectData{filename: filepath.Join(config.Global.WorkingDir, s.TemplateDir)}); err != nil {
+		return nil, err
+	}
+	if err = child.readIncludes(chain); err != nil {
+		return nil, err
+	}
+	return child, nil
+}
+
+// includesTree returns all nested includes of the stack recursively.
+func (s *Stack) includesTree() []*Stack {
+	res := []*Stack{}
+	for _, inc := range s.Includes {
+		res = append(res, inc)
+		res = append(res, inc.includesTree()...)
+	}
+	return res
+}
+
+// UnitNamespace returns the namespace prefix of the unit name ('addons:' for 'addons:ingress'),
+// or empty string for units which are not from included templates.
+func UnitNamespace(unitName string) string {
+	return unitName[:strings.LastIndex(unitName, unitsNamespaceSeparator)+1]
+}
+
+// UnitDirName returns the name of the unit cache dir for the unit key. The namespace separator is replaced with '.',
+// since ':' is not allowed in file names on some systems ('stack.addons.ingress' for 'stack.addons:ingress').
+func UnitDirName(unitKey string) string {
+	return strings.ReplaceAll(unitKey, unitsNamespaceSeparator, ".")
+}
+
+// namespacedUnitSpec returns the copy of unit spec with the name prefixed by the include namespace.
+func (s *Stack) namespacedUnitSpec(spec map[string]interface{}) map[string]interface{} {
+	if s.UnitsPrefix == "" {
+		return spec
+	}
+	res := make(map[string]interface{}, len(spec))
+	for k, v := range spec {
+		res[k] = v
+	}
+	if name, ok := spec["name"].(string); ok {
+		res["name"] = s.UnitsPrefix + name
+	}
+	return res
+}
@@ -0,0 +1,319 @@
+package project
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/apex/log"
+	"github.com/olekukonko/tablewriter"
+	"github.com/shalb/cluster.dev/internal/config"
+	"github.com/shalb/cluster.dev/pkg/utils"
+)
+
+const stackOutputLinkType = "stackOutput"
+
+// maxStackOutputsDepth limits the chain of stack outputs which refer to outputs of other stacks.
+const maxStackOutputsDepth = 10
+
+// stackOutputRef describes the reference to the stack output created by 'stackOutput' template function.
+type stackOutputRef struct {
+	StackName  string
+	OutputName string
+}
+
+// readStackOutputs joins 'outputs' sections of all stack templates. Outputs of included templates are added with
+// the include namespace, e.g. 'addons:ingress_ip'.
+func (s *Stack) readStackOutputs() error {
+	s.Outputs = map[string]interface{}{}
+	for _, stack := range append([]*Stack{s}, s.includesTree()...) {
+		for _, tmpl := range stack.Templates {
+			for name, val := range tmpl.Outputs {
+				name = stack.UnitsPrefix + name
+				if _, exists := s.Outputs[name]; exists {
+					return fmt.Errorf("stack '%v': output '%v' is declared in several stack templates", s.Name, name)
+				}
+				s.Outputs[name] = val
+			}
+		}
+	}
+	return nil
+}
+
+// addStackOutputMarker creates the marker for 'stackOutput' template function. Markers are replaced with
+// the stack output values after all stacks are read (see resolveStackOutputs).
+func (p *Project) addStackOutputMarker(path string, s *Stack) (string, error) {
+	splittedPath := strings.Split(path, ".")
+	if len(splittedPath) != 2 {
+		return "", fmt.Errorf("stackOutput tmpl: bad path '%v', expected 'stack_name.output_name'", path)
+	}
+	ref := stackOutputRef{
+		StackName:  splittedPath[0],
+		OutputName: splittedPath[1],
+	}
+	if ref.StackName == "this" {
+		if s == nil {
+			return "", fmt.Errorf("stackOutput tmpl: using 'this' allowed only in template, use stack name instead")
+		}
+		ref.StackName = s.Name
+	}
+	markerPath := fmt.Sprintf("%v.%v.%v", stackOutputLinkType, ref.StackName, ref.OutputName)
+	hash := utils.Md5(markerPath)
+	marker, err := EscapeForMarkerStr(fmt.Sprintf("%s.%s.%s", hash, markerPath, hash))
+	if err != nil {
+		return "", err
+	}
+	p.stackOutputMarkers[marker] = ref
+	return marker, nil
+}
+
+// resolveStackOutputs replaces stackOutput and remoteProjectOutput markers in stack outputs and units specs
+// with values.
+func (p *Project) resolveStackOutputs() error {
+	if len(p.stackOutputMarkers) == 0 && len(p.remoteProjectMarkers) == 0 {
+		return nil
+	}
+	stacks := []*Stack{}
+	for _, stack := range p.Stacks {
+		stacks = append(stacks, append([]*Stack{stack}, stack.includesTree()...)...)
+	}
+	// Stack outputs may refer to outputs of other stacks, resolve them first.
+	for i := 0; ; i++ {
+		changed := false
+		for _, stack := range stacks {
+			for name, val := range stack.Outputs {
+				res, err := p.replaceStackOutputMarkers(val, nil)
+				if err != nil {
+					return fmt.Errorf("stack '%v', output '%v': %w", stack.Name, name, err)
+				}
+				if fmt.Sprint(res) != fmt.Sprint(val) {
+					changed = true
+				}
+				stack.Outputs[name] = res
+			}
+		}
+		if !changed {
+			break
+		}
+		if i >= maxStackOutputsDepth {
+			return fmt.Errorf("stack outputs: too deep or circular references between stack outputs")
+		}
+	}
+	for _, stack := range stacks {
+		for _, tmpl := range stack.Templates {
+			for i, unitData := range tmpl.Units {
+				unitKey := fmt.Sprintf("%v.%v%v", stack.Name, stack.UnitsPrefix, unitData["name"])
+				res, err := p.replaceStackOutputMarkers(unitData, func(path string) {
+					p.RemoteProjectOutputs[path].UsedBy = append(p.RemoteProjectOutputs[path].UsedBy, unitKey)
+				})
+				if err != nil {
+					return fmt.Errorf("stack '%v', unit '%v': %w", stack.Name, unitData["name"], err)
+				}
+				tmpl.Units[i] = res.(map[string]interface{})
+			}
+		}
+	}
+	return nil
+}
+
+func (p *Project) stackOutputValue(ref stackOutputRef) (interface{}, error) {
+	stack, exists := p.Stacks[ref.StackName]
+	if !exists {
+		return nil, fmt.Errorf("stackOutput '%v.%v': stack '%v' does not exist or disabled", ref.StackName, ref.OutputName, ref.StackName)
+	}
+	val, exists := stack.Outputs[ref.OutputName]
+	if !exists {
+		return nil, fmt.Errorf("stackOutput '%v.%v': stack '%v' has no output '%v'", ref.StackName, ref.OutputName, ref.StackName, ref.OutputName)
+	}
+	return val, nil
+}
+
+// markerValue returns the value for stackOutput or remoteProjectOutput marker. onRemoteOutput is called with
+// the path of used remote project output.
+func (p *Project) markerValue(marker string, onRemoteOutput func(string)) (interface{}, error) {
+	if ref, exists := p.stackOutputMarkers[marker]; exists {
+		return p.stackOutputValue(ref)
+	}
+	path := p.remoteProjectMarkers[marker]
+	val, err := p.remoteProjectOutputValue(path)
+	if err != nil {
+		return nil, err
+	}
+	if onRemoteOutput != nil {
+		onRemoteOutput(path)
+	}
+	return val, nil
+}
+
+// replaceStackOutputMarkers returns the copy of data with replaced stackOutput and remoteProjectOutput markers.
+// A string which consists of single marker is replaced with value of any type.
+func (p *Project) replaceStackOutputMarkers(data interface{}, onRemoteOutput func(string)) (interface{}, error) {
+	switch typed := data.(type) {
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(typed))
+		for k, v := range typed {
+			val, err := p.replaceStackOutputMarkers(v, onRemoteOutput)
+			if err != nil {
+				return nil, err
+			}
+			res[k] = val
+		}
+		return res, nil
+	case []interface{}:
+		res := make([]interface{}, len(typed))
+		for i, v := range typed {
+			val, err := p.replaceStackOutputMarkers(v, onRemoteOutput)
+			if err != nil {
+				return nil, err
+			}
+			res[i] = val
+		}
+		return res, nil
+	case string:
+		_, isStackOutput := p.stackOutputMarkers[typed]
+		_, isRemoteOutput := p.remoteProjectMarkers[typed]
+		if isStackOutput || isRemoteOutput {
+			return p.markerValue(typed, onRemoteOutput)
+		}
+		res := typed
+		for _, markers := range []map[string]string{p.stackOutputMarkersList(), p.remoteProjectMarkers} {
+			for marker, path := range markers {
+				if !strings.Contains(res, marker) {
+					continue
+				}
+				val, err := p.markerValue(marker, onRemoteOutput)
+				if err != nil {
+					return nil, err
+				}
+				switch val.(type) {
+				case map[string]interface{}, []interface{}:
+					return nil, fmt.Errorf("'%v': can't insert object or list into a string", path)
+				}
+				res = strings.ReplaceAll(res, marker, fmt.Sprint(val))
+			}
+		}
+		return res, nil
+	}
+	return data, nil
+}
+
+// stackOutputMarkersList returns stackOutput markers with paths.
+func (p *Project) stackOutputMarkersList() map[string]string {
+	res := make(map[string]string, len(p.stackOutputMarkers))
+	for marker, ref := range p.stackOutputMarkers {
+		res[marker] = fmt.Sprintf("%v.%v", ref.StackName, ref.OutputName)
+	}
+	return res
+}
+
+// updateStackOutputs sets stack outputs data using outputs of applied units saved in the state.
+// Outputs which refer to units that were not applied yet are omitted.
+func (sp *StateProject) updateStackOutputs() {
+	res := map[string]map[string]interface{}{}
+	for stackName, outputs := range sp.StackOutputs {
+		if _, exists := sp.LoaderProjectPtr.Stacks[stackName]; !exists {
+			// Keep outputs of stacks which are not loaded.
+			res[stackName] = outputs
+		}
+	}
+	for stackName, stack := range sp.LoaderProjectPtr.Stacks {
+		for name, val := range stack.Outputs {
+			data, ok := sp.resolveOutputLinks(val)
+			if !ok {
+				continue
+			}
+			if res[stackName] == nil {
+				res[stackName] = map[string]interface{}{}
+			}
+			res[stackName][name] = data
+		}
+	}
+	sp.StackOutputs = res
+}
+
+// resolveOutputLinks replaces output markers with output data. Returns false if some outputs are unavailable.
+func (sp *StateProject) resolveOutputLinks(data interface{}) (interface{}, bool) {
+	switch typed := data.(type) {
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(typed))
+		for k, v := range typed {
+			val, ok := sp.resolveOutputLinks(v)
+			if !ok {
+				return nil, false
+			}
+			res[k] = val
+		}
+		return res, true
+	case []interface{}:
+		res := make([]interface{}, len(typed))
+		for i, v := range typed {
+			val, ok := sp.resolveOutputLinks(v)
+			if !ok {
+				return nil, false
+			}
+			res[i] = val
+		}
+		return res, true
+	case string:
+		res := typed
+		for marker, link := range sp.UnitLinks.ByLinkTypes(OutputLinkType).Map() {
+			if !strings.Contains(res, marker) {
+				continue
+			}
+			if link.OutputData == nil || sp.Units[link.UnitKey()] == nil {
+				return nil, false
+			}
+			if res == marker {
+				return link.OutputData, true
+			}
+			res = strings.ReplaceAll(res, marker, fmt.Sprint(link.OutputData))
+		}
+		if sp.LoaderProjectPtr.CheckContainsMarkers(res) {
+			return nil, false
+		}
+		return res, true
+	}
+	return data, true
+}
+
+// PrintStackOutputs prints outputs of all stacks saved in the state.
+func (p *Project) PrintStackOutputs() error {
+	if len(p.StackOutputs) == 0 {
+		return nil
+	}
+	if config.Global.OutputJSON {
+		res, err := utils.JSONEncodeString(p.StackOutputs)
+		if err != nil {
+			return err
+		}
+		fmt.Println(res)
+		return nil
+	}
+	stackNames := make([]string, 0, len(p.StackOutputs))
+	for name := range p.StackOutputs {
+		stackNames = append(stackNames, name)
+	}
+	sort.Strings(stackNames)
+	log.Info("Stack outputs:")
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Stack", "Output", "Value"})
+	for _, stackName := range stackNames {
+		outputNames := make([]string, 0, len(p.StackOutputs[stackName]))
+		for name := range p.StackOutputs[stackName] {
+			outputNames = append(outputNames, name)
+		}
+		sort.Strings(outputNames)
+		for _, name := range outputNames {
+			val := p.StackOutputs[stackName][name]
+			valStr, ok := val.(string)
+			if !ok {
+				valStr, _ = utils.JSONEncodeString(val)
+				valStr = strings.TrimSpace(valStr)
+			}
+			table.Append([]string{stackName, name, valStr})
+		}
+	}
+	table.Render()
+	return nil
+}
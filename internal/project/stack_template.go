@@ -18,6 +18,9 @@ type stackTemplate struct {
 	Units            []map[string]interface{} `yaml:"units"`
 	Modules          []map[string]interface{} `yaml:"modules,omitempty"`
 	ReqClientVersion string                   `yaml:"cliVersion"`
+	Variables        VariablesSchema          `yaml:"variables,omitempty"`
+	Includes         []templateInclude        `yaml:"includes,omitempty"`
+	Outputs          map[string]interface{}   `yaml:"outputs,omitempty"`
 }
 
 func NewStackTemplate(data []byte) (*stackTemplate, error) {
@@ -26,9 +29,9 @@ func NewStackTemplate(data []byte) (*stackTemplate, error) {
 	if err != nil {
 		return nil, fmt.Errorf("unmarshal template data: %v", utils.ResolveYamlError(data, err))
 	}
-	if len(iTmpl.Units) < 1 {
+	if len(iTmpl.Units) < 1 && len(iTmpl.Includes) < 1 {
 		if len(iTmpl.Modules) < 1 {
-			return nil, fmt.Errorf("parsing template: template must contain at least one unit")
+			return nil, fmt.Errorf("parsing template: template must contain at least one unit or include")
 		}
 		iTmpl.Units = iTmpl.Modules
 		iTmpl.Modules = nil
@@ -43,6 +46,9 @@ func NewStackTemplate(data []byte) (*stackTemplate, error) {
 		}
 		log.Warnf("'InfraTemplate' kind is deprecated and will be removed in future releases. Use 'StackTemplate' instead")
 	}
+	if err = iTmpl.Variables.check(iTmpl.Name); err != nil {
+		return nil, fmt.Errorf("parsing template: %w", err)
+	}
 	if iTmpl.ReqClientVersion != "" {
 		log.Debug("Checking client version...")
 		reqVerConstraints, err := semver.NewConstraint(iTmpl.ReqClientVersion)
@@ -0,0 +1,332 @@
+package project
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/apex/log"
+	"github.com/shalb/cluster.dev/internal/config"
+	"github.com/shalb/cluster.dev/pkg/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// templateTestsDirName is the dir in the stack template with test fixtures and golden files.
+const templateTestsDirName = "tests"
+
+const templateTestDefaultStackName = "test"
+
+const (
+	templateTestSpecFileName = "spec.yaml"
+	templateTestFilesDirName = "files"
+)
+
+// templateTestFixture describes tests/<case>.yaml file. Outputs contain stubbed values of outputs and remote states
+// of other stacks in the format 'stack.unit.output: value'.
+type templateTestFixture struct {
+	StackName string                 `yaml:"name"`
+	Variables map[string]interface{} `yaml:"variables"`
+	Outputs   map[string]interface{} `yaml:"outputs"`
+}
+
+// TemplateTestResult is the result of one test case of the stack template.
+type TemplateTestResult struct {
+	Case string
+	// Diffs contain differences with golden files by file path, empty when the test passed.
+	Diffs map[string]string
+	Err   error
+}
+
+// TestTemplate renders the stack template with each fixture from the tests dir, builds units and compares unit specs
+// and generated files with golden files in the tests/<case> dir. In update mode golden files are rewritten.
+func TestTemplate(templateDir string, update bool) ([]TemplateTestResult, error) {
+	templateDir, err := filepath.Abs(templateDir)
+	if err != nil {
+		return nil, err
+	}
+	testsDir := filepath.Join(templateDir, templateTestsDirName)
+	fixtures, err := filepath.Glob(filepath.Join(testsDir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	if len(fixtures) == 0 {
+		return nil, fmt.Errorf("no test fixtures found in '%v', add %v/<case>.yaml files", testsDir, templateTestsDirName)
+	}
+	sort.Strings(fixtures)
+	results := []TemplateTestResult{}
+	for _, fixtureFile := range fixtures {
+		caseName := strings.TrimSuffix(filepath.Base(fixtureFile), ".yaml")
+		res := TemplateTestResult{Case: caseName}
+		rendered, err := renderTemplateTestCase(templateDir, fixtureFile)
+		if err != nil {
+			res.Err = err
+			results = append(results, res)
+			continue
+		}
+		goldenDir := filepath.Join(testsDir, caseName)
+		if update {
+			res.Err = writeGoldenFiles(goldenDir, rendered)
+		} else {
+			res.Diffs, res.Err = compareGoldenFiles(goldenDir, rendered)
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+// renderTemplateTestCase creates a temporary project with the tested stack and stub stacks, loads and builds it.
+// Returns the content of golden files by path relative to the case dir.
+func renderTemplateTestCase(templateDir, fixtureFile string) (map[string]string, error) {
+	data, err := os.ReadFile(fixtureFile)
+	if err != nil {
+		return nil, err
+	}
+	fixture := templateTestFixture{}
+	if err = yaml.Unmarshal(data, &fixture); err != nil {
+		return nil, fmt.Errorf("read fixture: %v", utils.ResolveYamlError(data, err))
+	}
+	if fixture.StackName == "" {
+		fixture.StackName = templateTestDefaultStackName
+	}
+	if fixture.Variables == nil {
+		fixture.Variables = map[string]interface{}{}
+	}
+	projectDir, err := os.MkdirTemp("", "cdev-template-test-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(projectDir)
+	objects := []map[string]interface{}{
+		{"kind": "Project", "name": "template-test"},
+		{"kind": stackObjKindKey, "name": fixture.StackName, "backend": "default", "template": templateDir, "variables": fixture.Variables},
+	}
+	stubs, err := templateTestStubs(fixture.Outputs)
+	if err != nil {
+		return nil, err
+	}
+	for stackName, units := range stubs {
+		stubDir := filepath.Join(projectDir, "stubs", stackName)
+		stubTemplate := map[string]interface{}{"kind": stackTemplateObjKindKey, "name": stackName, "units": units}
+		if err = writeYAMLFile(filepath.Join(stubDir, "template.yaml"), stubTemplate); err != nil {
+			return nil, err
+		}
+		objects = append(objects, map[string]interface{}{"kind": stackObjKindKey, "name": stackName, "backend": "default", "template": stubDir, "variables": map[string]interface{}{}})
+	}
+	for i, obj := range objects {
+		if err = writeYAMLFile(filepath.Join(projectDir, fmt.Sprintf("%02d.yaml", i)), obj); err != nil {
+			return nil, err
+		}
+	}
+
+	// Run the project from the temporary dir, paths in the project are resolved from the current dir.
+	savedConfig := config.Global
+	defer func() { config.Global = savedConfig }()
+	curDir, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	if err = os.Chdir(projectDir); err != nil {
+		return nil, err
+	}
+	defer os.Chdir(curDir)
+	config.Global.WorkingDir = projectDir
+	config.Global.ProjectConfigsPath = projectDir
+	config.Global.WorkDir = filepath.Join(projectDir, ".cluster.dev")
+	config.Global.CacheDir = filepath.Join(config.Global.WorkDir, "cache/")
+	config.Global.StateCacheDir = config.Global.CacheDir
+	config.Global.TemplatesCacheDir = filepath.Join(config.Global.WorkDir, "templates")
+	config.Global.DownloadsCacheDir = filepath.Join(savedConfig.WorkDir, "downloads")
+	config.Global.TerraformCLIConfig = ""
+	config.Global.IgnoreState = true
+	config.Global.Env = ""
+	config.Global.ProjectConfig = ""
+	config.Global.Vars = nil
+	config.Global.VarFiles = nil
+	config.Global.Locked = false
+	config.Global.UpdateLock = false
+	config.Global.Vendoring = false
+
+	p, err := LoadProjectFull()
+	if err != nil {
+		return nil, err
+	}
+	// Stubbed values are inserted instead of outputs, remote states stay references to the stub units state.
+	for _, link := range p.UnitLinks.ByLinkTypes(OutputLinkType).Map() {
+		if value, exists := fixture.Outputs[fmt.Sprintf("%v.%v.%v", link.TargetStackName, link.TargetUnitName, link.OutputName)]; exists {
+			link.OutputData = value
+		}
+	}
+	if err = p.Build(); err != nil {
+		return nil, err
+	}
+	replacer := strings.NewReplacer(templateDir, "<template_dir>", projectDir, "<project_dir>")
+	res := map[string]string{}
+	for key, unit := range p.Units {
+		if unit.Stack().Name != fixture.StackName {
+			continue
+		}
+		unitName := UnitDirName(strings.TrimPrefix(key, fixture.StackName+"."))
+		spec, err := yaml.Marshal(unit.GetDiffData())
+		if err != nil {
+			return nil, fmt.Errorf("unit '%v': %w", key, err)
+		}
+		res[filepath.Join(unitName, templateTestSpecFileName)] = replacer.Replace(string(spec))
+		unitDir := filepath.Join(p.CodeCacheDir, UnitDirName(key))
+		if !utils.IsDir(unitDir) {
+			return nil, fmt.Errorf("unit '%v': cache dir '%v' not found after build", key, unitDir)
+		}
+		err = filepath.WalkDir(unitDir, func(path string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() && d.Name() == ".terraform" {
+				return filepath.SkipDir
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			rel, _ := filepath.Rel(unitDir, path)
+			fileData, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			res[filepath.Join(unitName, templateTestFilesDirName, rel)] = replacer.Replace(string(fileData))
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unit '%v': %w", key, err)
+		}
+	}
+	return res, nil
+}
+
+// templateTestStubs converts stubbed outputs to 'printer' units of stub stacks.
+func templateTestStubs(outputs map[string]interface{}) (map[string][]map[string]interface{}, error) {
+	unitsOutputs := map[string]map[string]map[string]interface{}{}
+	for path, value := range outputs {
+		splitted := strings.SplitN(path, ".", 3)
+		if len(splitted) != 3 {
+			return nil, fmt.Errorf("read fixture: bad output stub '%v', expected format 'stack.unit.output'", path)
+		}
+		stackName, unitName, outputName := splitted[0], splitted[1], splitted[2]
+		if _, exists := unitsOutputs[stackName]; !exists {
+			unitsOutputs[stackName] = map[string]map[string]interface{}{}
+		}
+		if _, exists := unitsOutputs[stackName][unitName]; !exists {
+			unitsOutputs[stackName][unitName] = map[string]interface{}{}
+		}
+		unitsOutputs[stackName][unitName][outputName] = value
+	}
+	res := map[string][]map[string]interface{}{}
+	for stackName, units := range unitsOutputs {
+		for unitName, unitOutputs := range units {
+			res[stackName] = append(res[stackName], map[string]interface{}{
+				"name":    unitName,
+				"type":    "printer",
+				"outputs": unitOutputs,
+			})
+		}
+	}
+	return res, nil
+}
+
+func writeYAMLFile(fn string, data interface{}) error {
+	out, err := yaml.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(fn), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(fn, out, 0644)
+}
+
+// writeGoldenFiles replaces the content of the golden dir with rendered files.
+func writeGoldenFiles(goldenDir string, rendered map[string]string) error {
+	if err := os.RemoveAll(goldenDir); err != nil {
+		return err
+	}
+	for fn, data := range rendered {
+		path := filepath.Join(goldenDir, fn)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			return err
+		}
+	}
+	log.Debugf("Golden files updated: %v", goldenDir)
+	return nil
+}
+
+// compareGoldenFiles returns differences between golden and rendered files by file path.
+func compareGoldenFiles(goldenDir string, rendered map[string]string) (map[string]string, error) {
+	if !utils.IsDir(goldenDir) {
+		return nil, fmt.Errorf("golden files not found in '%v', run with --update to create them", goldenDir)
+	}
+	golden := map[string]string{}
+	err := filepath.WalkDir(goldenDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(goldenDir, path)
+		golden[rel] = string(data)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	diffs := map[string]string{}
+	for fn, data := range rendered {
+		expected, exists := golden[fn]
+		if !exists {
+			diffs[fn] = "file is not in golden files"
+			continue
+		}
+		if expected != data {
+			diffs[fn] = utils.Diff(strings.Split(expected, "\n"), strings.Split(data, "\n"), !config.Global.NoColor)
+		}
+	}
+	for fn := range golden {
+		if _, exists := rendered[fn]; !exists {
+			diffs[fn] = "file is not rendered"
+		}
+	}
+	return diffs, nil
+}
+
+// PrintTemplateTestResults prints results of template tests with diffs of failed cases. Returns the number of failed
+// cases.
+func PrintTemplateTestResults(results []TemplateTestResult, update bool) int {
+	failed := 0
+	for _, res := range results {
+		switch {
+		case res.Err != nil:
+			failed++
+			log.Errorf("Test '%v': %v", res.Case, res.Err.Error())
+		case update:
+			log.Infof("Test '%v': golden files updated", res.Case)
+		case len(res.Diffs) == 0:
+			log.Infof("Test '%v': ok", res.Case)
+		default:
+			failed++
+			files := make([]string, 0, len(res.Diffs))
+			for fn := range res.Diffs {
+				files = append(files, fn)
+			}
+			sort.Strings(files)
+			msg := []string{}
+			for _, fn := range files {
+				msg = append(msg, fmt.Sprintf("%v:\n%v", fn, res.Diffs[fn]))
+			}
+			log.Errorf("Test '%v': rendered files do not match golden files:\n%v", res.Case, strings.Join(msg, "\n"))
+		}
+	}
+	return failed
+}
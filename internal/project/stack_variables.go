@@ -0,0 +1,332 @@
+package project
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/apex/log"
+	"github.com/shalb/cluster.dev/internal/config"
+	"github.com/shalb/cluster.dev/pkg/utils"
+)
+
+// VariableSpec describes a single stack variable declared in the 'variables' section of a stack template.
+type VariableSpec struct {
+	Type        string                   `yaml:"type,omitempty"`
+	Description string                   `yaml:"description,omitempty"`
+	Default     interface{}              `yaml:"default,omitempty"`
+	Required    bool                     `yaml:"required,omitempty"`
+	Enum        []interface{}            `yaml:"enum,omitempty"`
+	Regex       string                   `yaml:"regex,omitempty"`
+	Properties  map[string]*VariableSpec `yaml:"properties,omitempty"`
+	Items       *VariableSpec            `yaml:"items,omitempty"`
+}
+
+// VariablesSchema describes all variables of a stack template.
+type VariablesSchema map[string]*VariableSpec
+
+var variableTypes = map[string]bool{
+	"":        true,
+	"any":     true,
+	"string":  true,
+	"number":  true,
+	"bool":    true,
+	"boolean": true,
+	"list":    true,
+	"map":     true,
+	"object":  true,
+}
+
+// VariableError describes the validation error of the variable with the path inside stack 'variables' section.
+type VariableError struct {
+	path []string
+	msg  string
+}
+
+func (e VariableError) Error() string {
+	return fmt.Sprintf("variable '%s': %s", strings.Join(e.path, "."), e.msg)
+}
+
+// check validates schema declaration itself.
+func (v VariablesSchema) check(templateName string) error {
+	for name, spec := range v {
+		if err := spec.check([]string{name}); err != nil {
+			return fmt.Errorf("template '%v': variables schema: %w", templateName, err)
+		}
+	}
+	return nil
+}
+
+func (s *VariableSpec) check(path []string) error {
+	if s == nil {
+		return VariableError{path, "empty declaration"}
+	}
+	if !variableTypes[s.Type] {
+		return VariableError{path, fmt.Sprintf("unknown type '%v'", s.Type)}
+	}
+	if s.Regex != "" {
+		if _, err := regexp.Compile(s.Regex); err != nil {
+			return VariableError{path, fmt.Sprintf("bad regex: %v", err)}
+		}
+	}
+	if len(s.Properties) > 0 && s.Type != "object" && s.Type != "" {
+		return VariableError{path, fmt.Sprintf("'properties' allowed only for type 'object', not '%v'", s.Type)}
+	}
+	for name, prop := range s.Properties {
+		if err := prop.check(append(path, name)); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		if s.Type != "list" && s.Type != "map" {
+			return VariableError{path, fmt.Sprintf("'items' allowed only for types 'list' and 'map', not '%v'", s.Type)}
+		}
+		if err := s.Items.check(append(path, "items")); err != nil {
+			return err
+		}
+	}
+	if s.Default != nil {
+		if errs := s.validate(s.Default, path); len(errs) > 0 {
+			return VariableError{path, fmt.Sprintf("bad default value: %v", errs[0].msg)}
+		}
+	}
+	return nil
+}
+
+// Join adds variables from another schema, returns error if variable is declared twice.
+func (v VariablesSchema) Join(src VariablesSchema) error {
+	for name, spec := range src {
+		if _, exists := v[name]; exists {
+			return fmt.Errorf("variable '%v' is declared in several stack templates", name)
+		}
+		v[name] = spec
+	}
+	return nil
+}
+
+// ApplyDefaults sets default values for all missing variables. Returns true if some values were set.
+func (v VariablesSchema) ApplyDefaults(vars map[string]interface{}) bool {
+	applied := false
+	for name, spec := range v {
+		if spec.applyDefaults(vars, name) {
+			applied = true
+		}
+	}
+	return applied
+}
+
+func (s *VariableSpec) applyDefaults(vars map[string]interface{}, name string) bool {
+	val, exists := vars[name]
+	if !exists {
+		if s.Default == nil {
+			return false
+		}
+		vars[name] = s.Default
+		return true
+	}
+	nested, ok := val.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	applied := false
+	for propName, prop := range s.Properties {
+		if prop.applyDefaults(nested, propName) {
+			applied = true
+		}
+	}
+	return applied
+}
+
+// Validate checks variables with schema and returns the list of errors.
+func (v VariablesSchema) Validate(vars map[string]interface{}) []VariableError {
+	errs := validateFields(v, vars, []string{})
+	sort.SliceStable(errs, func(i, j int) bool {
+		return strings.Join(errs[i].path, ".") < strings.Join(errs[j].path, ".")
+	})
+	return errs
+}
+
+func validateFields(specs map[string]*VariableSpec, vars map[string]interface{}, path []string) (errs []VariableError) {
+	for name, spec := range specs {
+		fieldPath := append(append([]string{}, path...), name)
+		val, exists := vars[name]
+		if !exists || val == nil {
+			if spec.Required {
+				errs = append(errs, VariableError{fieldPath, "required variable is not set"})
+			}
+			continue
+		}
+		errs = append(errs, spec.validate(val, fieldPath)...)
+	}
+	for name := range vars {
+		if _, declared := specs[name]; !declared {
+			errs = append(errs, VariableError{append(append([]string{}, path...), name), "variable is not declared in the stack template"})
+		}
+	}
+	return
+}
+
+func (s *VariableSpec) validate(val interface{}, path []string) (errs []VariableError) {
+	if !matchType(s.Type, val) {
+		return []VariableError{{path, fmt.Sprintf("expected type '%v', got %v", s.Type, describeType(val))}}
+	}
+	if len(s.Enum) > 0 {
+		found := false
+		for _, e := range s.Enum {
+			if reflect.DeepEqual(e, val) || fmt.Sprintf("%v", e) == fmt.Sprintf("%v", val) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			errs = append(errs, VariableError{path, fmt.Sprintf("value '%v' is not one of %v", val, s.Enum)})
+		}
+	}
+	if s.Regex != "" {
+		str, ok := val.(string)
+		if !ok {
+			errs = append(errs, VariableError{path, "'regex' can be checked only for string values"})
+		} else if !regexp.MustCompile(s.Regex).MatchString(str) {
+			errs = append(errs, VariableError{path, fmt.Sprintf("value '%v' does not match regex '%v'", str, s.Regex)})
+		}
+	}
+	switch typed := val.(type) {
+	case map[string]interface{}:
+		if len(s.Properties) > 0 {
+			errs = append(errs, validateFields(s.Properties, typed, path)...)
+		}
+		if s.Items != nil {
+			for key, item := range typed {
+				errs = append(errs, s.Items.validate(item, append(append([]string{}, path...), key))...)
+			}
+		}
+	case []interface{}:
+		if s.Items != nil {
+			for i, item := range typed {
+				errs = append(errs, s.Items.validate(item, append(append([]string{}, path...), strconv.Itoa(i)))...)
+			}
+		}
+	}
+	return
+}
+
+func matchType(tp string, val interface{}) bool {
+	switch tp {
+	case "", "any":
+		return true
+	case "string":
+		_, ok := val.(string)
+		return ok
+	case "number":
+		switch val.(type) {
+		case int, int64, uint64, float64:
+			return true
+		}
+		return false
+	case "bool", "boolean":
+		_, ok := val.(bool)
+		return ok
+	case "list":
+		_, ok := val.([]interface{})
+		return ok
+	case "map", "object":
+		_, ok := val.(map[string]interface{})
+		return ok
+	}
+	return false
+}
+
+func describeType(val interface{}) string {
+	switch val.(type) {
+	case string:
+		return "string"
+	case int, int64, uint64, float64:
+		return "number"
+	case bool:
+		return "bool"
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "map"
+	}
+	return fmt.Sprintf("%T", val)
+}
+
+// variablesSchema returns joined variables schema of all stack templates.
+func (s *Stack) variablesSchema() (VariablesSchema, error) {
+	res := VariablesSchema{}
+	for _, tmpl := range s.Templates {
+		if err := res.Join(tmpl.Variables); err != nil {
+			return nil, fmt.Errorf("stack '%v': %w", s.Name, err)
+		}
+	}
+	return res, nil
+}
+
+// applyVariablesSchema sets default values of stack variables and validates them with stack template schema.
+// Templates are re-rendered if some default values were applied.
+func (s *Stack) applyVariablesSchema(stackSpec ObjectData) error {
+	schema, err := s.variablesSchema()
+	if err != nil {
+		return err
+	}
+	if len(schema) == 0 {
+		return nil
+	}
+	applied := schema.ApplyDefaults(s.Variables)
+	errs := schema.Validate(s.Variables)
+	if len(errs) > 0 {
+		fileName, err := filepath.Rel(config.Global.WorkingDir, stackSpec.filename)
+		if err != nil {
+			fileName = stackSpec.filename
+		}
+		msgs := []string{}
+		for _, e := range errs {
+			msgs = append(msgs, s.variableErrorMessage(e, stackSpec, fileName))
+		}
+		return fmt.Errorf("stack '%v': variables validation failed:\n%v", s.Name, strings.Join(msgs, "\n"))
+	}
+	if applied {
+		log.Debugf("Stack '%v': default variables applied, rendering templates again", s.Name)
+		return s.readTemplateFiles()
+	}
+	return nil
+}
+
+// variableErrorMessage returns the validation error with the location of the value: the variables file or the option
+// which set the value, or the stack file for values set in it and for defaults.
+func (s *Stack) variableErrorMessage(e VariableError, stackSpec ObjectData, fileName string) string {
+	source := s.variableSource(strings.Join(e.path, "."))
+	switch {
+	case source == varOptionSource:
+		return fmt.Sprintf("%v: %v", source, e.Error())
+	case strings.HasPrefix(source, varFileOptionSource+" "):
+		// '--var-file' contains variables of all stacks.
+		fn := strings.TrimPrefix(source, varFileOptionSource+" ")
+		return variableFileErrorMessage(e, source, fn, append([]string{s.Name}, e.path...))
+	case source != fileName && source != defaultVariablesSource:
+		// The file from 'variables_files'.
+		return variableFileErrorMessage(e, source, source, e.path)
+	}
+	line := utils.FindYAMLObjectLine(stackSpec.rawData, s.Name, append([]string{"variables"}, e.path...)...)
+	if line > 0 {
+		return utils.ResolveYamlError(stackSpec.rawData, fmt.Errorf("%v: line %d: %w", fileName, line, e)).Error()
+	}
+	return fmt.Sprintf("%v: %v", fileName, e.Error())
+}
+
+// variableFileErrorMessage returns the validation error with the line of the value in the variables file.
+func variableFileErrorMessage(e VariableError, source, fn string, path []string) string {
+	data, err := os.ReadFile(variablesFilePath(fn))
+	if err == nil {
+		if line := utils.FindYAMLLine(data, path...); line > 0 {
+			return utils.ResolveYamlError(data, fmt.Errorf("%v: line %d: %w", source, line, e)).Error()
+		}
+	}
+	return fmt.Sprintf("%v: %v", source, e.Error())
+}
@@ -0,0 +1,252 @@
+package project
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/shalb/cluster.dev/internal/config"
+	"github.com/shalb/cluster.dev/pkg/utils"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	varOptionSource        = "--var"
+	varFileOptionSource    = "--var-file"
+	defaultVariablesSource = "default"
+)
+
+// stackVariablesOverride describes stack variables set in env overlay or with '--var-file' and '--var' options.
+type stackVariablesOverride struct {
+	source    string
+	variables map[string]interface{}
+}
+
+// readVariablesOverrides reads '--var-file' and '--var' options. Files contain maps with stack names as top level keys,
+// '--var' options have format 'stack_name.path.to.variable=value'. Overrides are applied after env overlays
+// in the order: files, vars.
+func (p *Project) readVariablesOverrides() error {
+	for _, fn := range config.Global.VarFiles {
+		data, err := os.ReadFile(variablesFilePath(fn))
+		if err != nil {
+			return fmt.Errorf("--var-file: %w", err)
+		}
+		var stacksVars map[string]interface{}
+		if err = yaml.Unmarshal(data, &stacksVars); err != nil {
+			return fmt.Errorf("--var-file '%v': %v", fn, utils.ResolveYamlError(data, err))
+		}
+		for stackName, vars := range stacksVars {
+			varsMap, ok := vars.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("--var-file '%v': variables of stack '%v' should be a map, not %T", fn, stackName, vars)
+			}
+			p.variablesOverrides[stackName] = append(p.variablesOverrides[stackName], stackVariablesOverride{
+				source:    varFileOptionSource + " " + fn,
+				variables: varsMap,
+			})
+		}
+	}
+	for _, v := range config.Global.Vars {
+		path, valueStr, found := strings.Cut(v, "=")
+		splittedPath := strings.Split(path, ".")
+		if !found || len(splittedPath) < 2 {
+			return fmt.Errorf("--var '%v': expected format 'stack_name.variable=value'", v)
+		}
+		for _, key := range splittedPath {
+			if key == "" {
+				return fmt.Errorf("--var '%v': empty key in the variable path", v)
+			}
+		}
+		// Parse value as yaml to keep numbers and booleans typed.
+		var value interface{}
+		if err := yaml.Unmarshal([]byte(valueStr), &value); err != nil || value == nil {
+			value = valueStr
+		}
+		for i := len(splittedPath) - 1; i > 0; i-- {
+			value = map[string]interface{}{splittedPath[i]: value}
+		}
+		stackName := splittedPath[0]
+		p.variablesOverrides[stackName] = append(p.variablesOverrides[stackName], stackVariablesOverride{
+			source:    varOptionSource,
+			variables: value.(map[string]interface{}),
+		})
+	}
+	return nil
+}
+
+// checkVariablesOverrides returns an error if variables are set for stacks which are not declared in the project.
+func (p *Project) checkVariablesOverrides() error {
+	declared := map[string]bool{}
+	for _, kind := range []string{stackObjKindKey, "Infrastructure"} {
+		for _, obj := range p.objects[kind] {
+			if name, ok := obj.data["name"].(string); ok {
+				declared[name] = true
+			}
+		}
+	}
+	for stackName, overrides := range p.variablesOverrides {
+		if !declared[stackName] {
+			return fmt.Errorf("%v: stack '%v' not found", overrides[0].source, stackName)
+		}
+	}
+	return nil
+}
+
+// readStackVariables builds stack variables from 'variables_files', 'variables', env overlay and command line
+// overrides, merged in this order. The source of each value is saved to VariablesSources.
+func (s *Stack) readStackVariables(stackSpec ObjectData) error {
+	s.Variables = map[string]interface{}{}
+	s.VariablesSources = map[string]string{}
+	varsFiles, hasFiles := stackSpec.data["variables_files"]
+	inlineVars, hasInline := stackSpec.data["variables"]
+	if !hasFiles && !hasInline {
+		return fmt.Errorf("stack object must contain field 'variables' or 'variables_files'")
+	}
+	if hasFiles {
+		filesList, ok := varsFiles.([]interface{})
+		if !ok {
+			return fmt.Errorf("stack option 'variables_files' should be a list, not %T", varsFiles)
+		}
+		for _, f := range filesList {
+			fn, ok := f.(string)
+			if !ok {
+				return fmt.Errorf("stack option 'variables_files': file name should be a string, not %T", f)
+			}
+			vars, err := readVariablesFile(fn)
+			if err != nil {
+				return fmt.Errorf("stack option 'variables_files': %w", err)
+			}
+			s.mergeVariables(vars, fn)
+		}
+	}
+	if hasInline && inlineVars != nil {
+		vars, ok := inlineVars.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("stack option 'variables' should be a map, not %T", inlineVars)
+		}
+		source, err := filepath.Rel(config.Global.WorkingDir, stackSpec.filename)
+		if err != nil {
+			source = stackSpec.filename
+		}
+		s.mergeVariables(vars, source)
+	}
+	for _, override := range s.ProjectPtr.variablesOverrides[s.Name] {
+		source := override.source
+		if rel, err := filepath.Rel(config.Global.WorkingDir, source); err == nil && utils.IsAbsolutePath(source) {
+			source = rel
+		}
+		s.mergeVariables(override.variables, source)
+	}
+	s.ConfigData["variables"] = s.Variables
+	return nil
+}
+
+// variablesFilePath returns the path of the variables file, relative paths are relative to the project dir.
+func variablesFilePath(fn string) string {
+	if utils.IsAbsolutePath(fn) {
+		return fn
+	}
+	return filepath.Join(config.Global.WorkingDir, fn)
+}
+
+// readVariablesFile reads yaml or json file with stack variables. Path is relative to the project dir.
+func readVariablesFile(fn string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(variablesFilePath(fn))
+	if err != nil {
+		return nil, err
+	}
+	vars := map[string]interface{}{}
+	if err = yaml.Unmarshal(data, &vars); err != nil {
+		return nil, fmt.Errorf("'%v': %v", fn, utils.ResolveYamlError(data, err))
+	}
+	return vars, nil
+}
+
+// mergeVariables deep-merges vars into stack variables and sets the source of merged values.
+func (s *Stack) mergeVariables(vars map[string]interface{}, source string) {
+	s.Variables = mergeVariablesWithSources(s.Variables, vars, "", source, s.VariablesSources)
+}
+
+func mergeVariablesWithSources(base, overlay map[string]interface{}, prefix, source string, sources map[string]string) map[string]interface{} {
+	res := make(map[string]interface{}, len(base))
+	for k, v := range base {
+		res[k] = v
+	}
+	for k, v := range overlay {
+		path := prefix + k
+		baseMap, baseIsMap := res[k].(map[string]interface{})
+		overlayMap, overlayIsMap := v.(map[string]interface{})
+		if baseIsMap && overlayIsMap {
+			res[k] = mergeVariablesWithSources(baseMap, overlayMap, path+".", source, sources)
+			continue
+		}
+		res[k] = v
+		for p := range sources {
+			if strings.HasPrefix(p, path+".") {
+				delete(sources, p)
+			}
+		}
+		sources[path] = source
+	}
+	return res
+}
+
+// variableSource returns the source of the variable value. Values, which were not set explicitly, are defaults
+// from the stack template variables schema.
+func (s *Stack) variableSource(path string) string {
+	for p := path; ; {
+		if source, exists := s.VariablesSources[p]; exists {
+			return source
+		}
+		i := strings.LastIndex(p, ".")
+		if i < 0 {
+			return defaultVariablesSource
+		}
+		p = p[:i]
+	}
+}
+
+// flattenVariables returns the map of variables leaf values by dot separated paths. Lists are not expanded.
+func flattenVariables(data map[string]interface{}, prefix string, res map[string]interface{}) {
+	for k, v := range data {
+		if m, ok := v.(map[string]interface{}); ok && len(m) > 0 {
+			flattenVariables(m, prefix+k+".", res)
+			continue
+		}
+		res[prefix+k] = v
+	}
+}
+
+// PrintVariables prints effective variables of all stacks with sources of the values.
+func (p *Project) PrintVariables() {
+	stackNames := make([]string, 0, len(p.Stacks))
+	for name := range p.Stacks {
+		stackNames = append(stackNames, name)
+	}
+	sort.Strings(stackNames)
+	fmt.Println("Variables:")
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Stack", "Variable", "Value", "Source"})
+	for _, stackName := range stackNames {
+		stack := p.Stacks[stackName]
+		vars := map[string]interface{}{}
+		flattenVariables(stack.Variables, "", vars)
+		paths := make([]string, 0, len(vars))
+		for path := range vars {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			val, ok := vars[path].(string)
+			if !ok {
+				val, _ = utils.JSONEncodeString(vars[path])
+				val = strings.TrimSpace(val)
+			}
+			table.Append([]string{stackName, path, val, stack.variableSource(path)})
+		}
+	}
+	table.Render()
+}
@@ -26,10 +26,14 @@ func (sp *StateProject) UpdateUnit(unit Unit) {
 	sp.Units[unit.Key()] = unit
 	sp.ChangedUnits[unit.Key()] = unit
 	sp.UnitLinks.Join(sp.LoaderProjectPtr.UnitLinks.ByTargetUnit(unit))
+	sp.updateStackOutputs()
 }
 
 func (sp *StateProject) DeleteUnit(mod Unit) {
+	sp.StateMutex.Lock()
+	defer sp.StateMutex.Unlock()
 	delete(sp.Units, mod.Key())
+	sp.updateStackOutputs()
 }
 
 func (sp *stateData) ClearULinks() {
@@ -50,10 +54,11 @@ func (p *Project) SaveState() error {
 	p.StateMutex.Lock()
 	defer p.StateMutex.Unlock()
 	st := stateData{
-		CdevVersion: config.Global.Version,
-		UnitLinks:   p.UnitLinks,
-		ProjectUUID: p.UUID,
-		Units:       map[string]interface{}{},
+		CdevVersion:  config.Global.Version,
+		UnitLinks:    p.UnitLinks,
+		ProjectUUID:  p.UUID,
+		Units:        map[string]interface{}{},
+		StackOutputs: p.StackOutputs,
 	}
 	// log.Errorf("units links: %+v\n Project: %+v", st.UnitLinks, p.UnitLinks)
 	for key, unit := range p.Units {
@@ -84,6 +89,8 @@ type stateData struct {
 	ProjectUUID string                 `json:"project_uuid,omitempty"`
 	UnitLinks   *UnitLinksT            `json:"unit_links"`
 	Units       map[string]interface{} `json:"units"`
+	// StackOutputs contains values of stack outputs declared in stack templates.
+	StackOutputs map[string]map[string]interface{} `json:"stack_outputs,omitempty"`
 }
 
 func (p *Project) LockState() error {
@@ -177,6 +184,7 @@ func (p *Project) NewEmptyState() *StateProject {
 			StateMutex:       sync.Mutex{},
 			InitLock:         sync.Mutex{},
 			UUID:             p.UUID,
+			StackOutputs:     make(map[string]map[string]interface{}),
 		},
 		LoaderProjectPtr: p,
 		ChangedUnits:     make(map[string]Unit),
@@ -221,6 +229,9 @@ func (p *Project) LoadState() (*StateProject, error) {
 	}
 	statePrj := p.NewEmptyState()
 	statePrj.UnitLinks = stateD.UnitLinks
+	if stateD.StackOutputs != nil {
+		statePrj.StackOutputs = stateD.StackOutputs
+	}
 	for mName, mState := range stateD.Units {
 		if mState == nil {
 			continue
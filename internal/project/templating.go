@@ -191,6 +191,7 @@ func (d *GlobalTemplateDriver) AddTemplateFunctions(mp template.FuncMap, p *Proj
 		}
 		if dep.TargetStackName == "this" {
 			dep.TargetStackName = s.Name
+			dep.TargetUnitName = s.UnitsPrefix + dep.TargetUnitName
 		}
 		return p.UnitLinks.Set(&dep)
 	}
@@ -198,6 +199,10 @@ func (d *GlobalTemplateDriver) AddTemplateFunctions(mp template.FuncMap, p *Proj
 	funcs := map[string]interface{}{
 		"insertYAML": InsertYaml,
 		"output":     addOutputMarkerFunk,
+		"stackOutput": func(path string) (string, error) {
+			return p.addStackOutputMarker(path, s)
+		},
+		"remoteProjectOutput": p.remoteProjectOutput,
 	}
 	for k, f := range funcs {
 		_, ok := mp[k]
@@ -36,6 +36,9 @@ func (u *ULinkT) InitUnitPtr(p *Project) (err error) {
 	modKey := fmt.Sprintf("%s.%s", u.TargetStackName, u.TargetUnitName)
 	depUnit, exists := p.Units[modKey]
 	if !exists {
+		if err := p.CheckUnitDisabled(modKey); err != nil {
+			return fmt.Errorf("link to disabled unit: %w", err)
+		}
 		return fmt.Errorf("link unit does not exists '%s'", modKey)
 	}
 	u.Unit = depUnit
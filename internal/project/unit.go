@@ -35,6 +35,11 @@ type Unit interface {
 	ExecError() error
 }
 
+// UnitValidator is an optional interface for units which support static validation of the configuration (used by 'cdev validate').
+type UnitValidator interface {
+	Validate() error
+}
+
 type UnitDriver interface {
 	AddTemplateFunctions(projectPtr *Project) error
 	GetScanners() []MarkerScanner
@@ -0,0 +1,71 @@
+package project
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const unitEnabledKey = "enabled"
+
+// unitEnabled evaluates unit 'enabled' option and returns the unit spec without this option.
+// The option can be a bool, a string with bool value or the path to the bool stack variable ('monitoring.enabled').
+// The path can be negated with '!'. Returns the reason message for disabled unit.
+func unitEnabled(spec map[string]interface{}, stack *Stack) (res map[string]interface{}, enabled bool, reason string, err error) {
+	expr, exists := spec[unitEnabledKey]
+	if !exists {
+		return spec, true, "", nil
+	}
+	res = make(map[string]interface{}, len(spec))
+	for k, v := range spec {
+		if k != unitEnabledKey {
+			res[k] = v
+		}
+	}
+	switch typed := expr.(type) {
+	case bool:
+		enabled = typed
+	case string:
+		enabled, err = evalEnabledExpr(strings.TrimSpace(typed), stack)
+		if err != nil {
+			return nil, false, "", fmt.Errorf("unit option 'enabled': %w", err)
+		}
+	default:
+		return nil, false, "", fmt.Errorf("unit option 'enabled' should be bool or string, not %T", expr)
+	}
+	return res, enabled, fmt.Sprintf("enabled: %v", expr), nil
+}
+
+func evalEnabledExpr(expr string, stack *Stack) (bool, error) {
+	if b, err := strconv.ParseBool(expr); err == nil {
+		return b, nil
+	}
+	negate := strings.HasPrefix(expr, "!")
+	path := strings.TrimSpace(strings.TrimPrefix(expr, "!"))
+	val, err := lookupPath(stack.Variables, strings.TrimPrefix(path, "variables."))
+	if err != nil {
+		return false, fmt.Errorf("stack variable '%v': %w", path, err)
+	}
+	var b bool
+	switch typed := val.(type) {
+	case bool:
+		b = typed
+	case string:
+		b, err = strconv.ParseBool(typed)
+		if err != nil {
+			return false, fmt.Errorf("stack variable '%v': %w", path, err)
+		}
+	default:
+		return false, fmt.Errorf("stack variable '%v' should be bool, not %T", path, val)
+	}
+	return b != negate, nil
+}
+
+// CheckUnitDisabled returns an error if the unit with the key 'stack.unit' is disabled by 'enabled' option.
+// Used to report references to disabled units.
+func (p *Project) CheckUnitDisabled(key string) error {
+	if reason, disabled := p.DisabledUnits[key]; disabled {
+		return fmt.Errorf("unit '%v' is disabled (%v)", key, reason)
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+package project
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	unitForEachKey = "for_each"
+	unitCountKey   = "count"
+)
+
+var (
+	forEachKeyRe      = regexp.MustCompile(`^[a-zA-Z0-9_\-]+$`)
+	eachPlaceholderRe = regexp.MustCompile(`\$\{each\.(key|value)((?:\.[a-zA-Z0-9_\-]+)*)\}`)
+)
+
+// eachInstance describes one element of unit 'for_each' or 'count' expansion.
+type eachInstance struct {
+	key   string
+	value interface{}
+}
+
+// expandUnitSpec expands unit spec with 'for_each' or 'count' option to the list of specs, one per element.
+// Instance unit is named 'name[key]'. Placeholders ${each.key}, ${each.value} and ${each.value.<path>}
+// in the unit spec are replaced by element values. Unit spec without these options is returned as is.
+func expandUnitSpec(spec map[string]interface{}, stack *Stack) ([]map[string]interface{}, error) {
+	forEach, hasForEach := spec[unitForEachKey]
+	count, hasCount := spec[unitCountKey]
+	if !hasForEach && !hasCount {
+		return []map[string]interface{}{spec}, nil
+	}
+	if hasForEach && hasCount {
+		return nil, fmt.Errorf("unit options 'for_each' and 'count' can't be used together")
+	}
+	uName, ok := spec["name"].(string)
+	if !ok {
+		return nil, fmt.Errorf("incorrect unit name")
+	}
+	var instances []eachInstance
+	var err error
+	if hasForEach {
+		instances, err = forEachInstances(forEach, stack)
+	} else {
+		instances, err = countInstances(count)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unit '%v': %w", uName, err)
+	}
+	res := make([]map[string]interface{}, 0, len(instances))
+	for _, inst := range instances {
+		instSpec := make(map[string]interface{}, len(spec))
+		for k, v := range spec {
+			if k == unitForEachKey || k == unitCountKey {
+				continue
+			}
+			val, err := replaceEachPlaceholders(v, inst)
+			if err != nil {
+				return nil, fmt.Errorf("unit '%v[%v]': %w", uName, inst.key, err)
+			}
+			instSpec[k] = val
+		}
+		instSpec["name"] = fmt.Sprintf("%s[%s]", uName, inst.key)
+		res = append(res, instSpec)
+	}
+	return res, nil
+}
+
+// forEachInstances returns elements of 'for_each' value. The value can be a list, a map or a string with
+// the path to the stack variable ('tenants' or 'network.zones').
+func forEachInstances(forEach interface{}, stack *Stack) ([]eachInstance, error) {
+	if path, ok := forEach.(string); ok {
+		val, err := lookupPath(stack.Variables, strings.TrimPrefix(path, "variables."))
+		if err != nil {
+			return nil, fmt.Errorf("for_each: stack variable '%v': %w", path, err)
+		}
+		forEach = val
+	}
+	var res []eachInstance
+	switch typed := forEach.(type) {
+	case map[string]interface{}:
+		for key, val := range typed {
+			res = append(res, eachInstance{key: key, value: val})
+		}
+		sort.Slice(res, func(i, j int) bool { return res[i].key < res[j].key })
+	case []interface{}:
+		for i, val := range typed {
+			key, err := listElementKey(val)
+			if err != nil {
+				return nil, fmt.Errorf("for_each: element %v: %w", i, err)
+			}
+			res = append(res, eachInstance{key: key, value: val})
+		}
+	default:
+		return nil, fmt.Errorf("for_each: expected list or map, got %T", forEach)
+	}
+	keys := map[string]bool{}
+	for _, inst := range res {
+		if !forEachKeyRe.MatchString(inst.key) {
+			return nil, fmt.Errorf("for_each: bad key '%v', only letters, digits, '_' and '-' are allowed", inst.key)
+		}
+		if keys[inst.key] {
+			return nil, fmt.Errorf("for_each: duplicate key '%v'", inst.key)
+		}
+		keys[inst.key] = true
+	}
+	return res, nil
+}
+
+// listElementKey returns the key of list element. Scalars are used as keys, objects must contain 'key' or 'name' field.
+// Index is not used to keep keys stable when elements are removed from the middle of the list.
+func listElementKey(val interface{}) (string, error) {
+	switch typed := val.(type) {
+	case map[string]interface{}:
+		for _, field := range []string{"key", "name"} {
+			if k, exists := typed[field]; exists {
+				return fmt.Sprintf("%v", k), nil
+			}
+		}
+		return "", fmt.Errorf("object element must contain 'key' or 'name' field")
+	case []interface{}, nil:
+		return "", fmt.Errorf("unsupported element type %T", val)
+	}
+	return fmt.Sprintf("%v", val), nil
+}
+
+func countInstances(count interface{}) ([]eachInstance, error) {
+	var n int
+	switch typed := count.(type) {
+	case int:
+		n = typed
+	case string:
+		var err error
+		n, err = strconv.Atoi(typed)
+		if err != nil {
+			return nil, fmt.Errorf("count: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("count: expected integer, got %T", count)
+	}
+	if n < 0 {
+		return nil, fmt.Errorf("count: negative value %v", n)
+	}
+	res := make([]eachInstance, n)
+	for i := range res {
+		res[i] = eachInstance{key: strconv.Itoa(i), value: i}
+	}
+	return res, nil
+}
+
+// lookupPath returns the value by dot separated path.
+func lookupPath(data interface{}, path string) (interface{}, error) {
+	if path == "" {
+		return data, nil
+	}
+	res := data
+	for _, k := range strings.Split(path, ".") {
+		m, ok := res.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("'%v' is not a map", k)
+		}
+		res, ok = m[k]
+		if !ok {
+			return nil, fmt.Errorf("key '%v' not found", k)
+		}
+	}
+	return res, nil
+}
+
+// replaceEachPlaceholders returns the copy of data with replaced ${each.*} placeholders.
+// A string which consists of single placeholder is replaced with value of any type, otherwise the value is
+// inserted into the string.
+func replaceEachPlaceholders(data interface{}, inst eachInstance) (interface{}, error) {
+	switch typed := data.(type) {
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(typed))
+		for k, v := range typed {
+			val, err := replaceEachPlaceholders(v, inst)
+			if err != nil {
+				return nil, err
+			}
+			res[k] = val
+		}
+		return res, nil
+	case []interface{}:
+		res := make([]interface{}, len(typed))
+		for i, v := range typed {
+			val, err := replaceEachPlaceholders(v, inst)
+			if err != nil {
+				return nil, err
+			}
+			res[i] = val
+		}
+		return res, nil
+	case string:
+		if loc := eachPlaceholderRe.FindStringIndex(typed); loc != nil && loc[0] == 0 && loc[1] == len(typed) {
+			return eachPlaceholderValue(typed, inst)
+		}
+		var resErr error
+		res := eachPlaceholderRe.ReplaceAllStringFunc(typed, func(s string) string {
+			val, err := eachPlaceholderValue(s, inst)
+			if err != nil {
+				resErr = err
+				return s
+			}
+			switch val.(type) {
+			case map[string]interface{}, []interface{}:
+				resErr = fmt.Errorf("placeholder '%v': can't insert object or list into a string", s)
+				return s
+			}
+			return fmt.Sprintf("%v", val)
+		})
+		return res, resErr
+	}
+	return data, nil
+}
+
+func eachPlaceholderValue(placeholder string, inst eachInstance) (interface{}, error) {
+	m := eachPlaceholderRe.FindStringSubmatch(placeholder)
+	if m[1] == "key" {
+		if m[2] != "" {
+			return nil, fmt.Errorf("placeholder '%v': each.key has no fields", placeholder)
+		}
+		return inst.key, nil
+	}
+	val, err := lookupPath(inst.value, strings.TrimPrefix(m[2], "."))
+	if err != nil {
+		return nil, fmt.Errorf("placeholder '%v': %w", placeholder, err)
+	}
+	return val, nil
+}
+
+// ConvertToHCLName converts unit name created by 'for_each' or 'count' ('name[key]') or included template
+// ('include:name') to the string which can be used as terraform block label. Other names are returned unchanged.
+func ConvertToHCLName(name string) string {
+	return strings.NewReplacer("[", "_", "]", "", unitsNamespaceSeparator, "_").Replace(name)
+}
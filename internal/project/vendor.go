@@ -0,0 +1,373 @@
+package project
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/apex/log"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/olekukonko/tablewriter"
+	"github.com/shalb/cluster.dev/internal/config"
+	"github.com/shalb/cluster.dev/pkg/executor"
+	"github.com/shalb/cluster.dev/pkg/utils"
+	"github.com/zclconf/go-cty/cty"
+	"gopkg.in/yaml.v3"
+)
+
+// vendorDirName is the dir in the project with vendored sources, created by 'cdev vendor'.
+const vendorDirName = "vendor"
+
+const vendorChecksumsFileName = "checksums.yaml"
+
+const vendorChecksumsHeader = "# This file is maintained by 'cdev vendor', do not edit it manually.\n"
+
+const (
+	vendoredFileKind = "file"
+	vendoredHelmKind = "helm"
+)
+
+// VendoredSource describes the remote source copied to the vendor dir.
+type VendoredSource struct {
+	Kind string `yaml:"kind"`
+	// Path is relative to the project dir.
+	Path string `yaml:"path"`
+	Hash string `yaml:"hash"`
+}
+
+// vendorSpec describes vendor/checksums.yaml file.
+type vendorSpec struct {
+	Sources map[string]*VendoredSource `yaml:"sources"`
+	// pending contains sources collected by 'cdev vendor' to be copied to the vendor dir.
+	pending map[string]pendingVendorSource
+	// stagingDir contains copies of pending source dirs.
+	stagingDir string
+}
+
+type pendingVendorSource struct {
+	kind     string
+	dir      string
+	data     []byte
+	helm     helmChartRef
+	tfModule tfModuleRef
+}
+
+type helmChartRef struct {
+	repository string
+	chart      string
+	version    string
+}
+
+// tfModuleRef is the terraform module from the registry or other non-git source, downloaded with 'terraform get'.
+type tfModuleRef struct {
+	source  string
+	version string
+	tfBin   string
+}
+
+// readVendor reads vendor/checksums.yaml. In vendoring mode vendored sources are not used.
+func (p *Project) readVendor() error {
+	p.vendor = &vendorSpec{
+		Sources: map[string]*VendoredSource{},
+		pending: map[string]pendingVendorSource{},
+	}
+	if config.Global.Vendoring {
+		return nil
+	}
+	checksumsFile := filepath.Join(config.Global.WorkingDir, vendorDirName, vendorChecksumsFileName)
+	data, err := os.ReadFile(checksumsFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("reading vendor checksums: %w", err)
+	}
+	if err = yaml.Unmarshal(data, p.vendor); err != nil {
+		return fmt.Errorf("reading vendor checksums: %v", utils.ResolveYamlError(data, err))
+	}
+	if p.vendor.Sources == nil {
+		p.vendor.Sources = map[string]*VendoredSource{}
+	}
+	return nil
+}
+
+// VendoredSource returns the absolute path to the vendored copy of the source, checking its checksum.
+func (p *Project) VendoredSource(src string) (string, bool, error) {
+	vs, exists := p.vendor.Sources[src]
+	if !exists {
+		return "", false, nil
+	}
+	path := filepath.Join(config.Global.WorkingDir, vs.Path)
+	hash, err := vendoredHash(path)
+	if err != nil {
+		return "", false, fmt.Errorf("vendored source '%v': %w", src, err)
+	}
+	if hash != vs.Hash {
+		return "", false, fmt.Errorf("vendored source '%v': checksum mismatch, '%v' was modified, run 'cdev vendor' to restore it", src, vs.Path)
+	}
+	log.Debugf("Using vendored source '%v': %v", src, vs.Path)
+	return path, true, nil
+}
+
+// addVendorDir adds the dir with the source content to be vendored. The dir is copied to the staging dir right away,
+// because the templates cache has one checkout per repository, which is changed when other refs of it are loaded.
+func (p *Project) addVendorDir(src, kind, dir string) error {
+	if !config.Global.Vendoring {
+		return nil
+	}
+	if _, exists := p.vendor.pending[src]; exists {
+		return nil
+	}
+	if p.vendor.stagingDir == "" {
+		// Copies left by the failed 'cdev vendor' are removed.
+		stagingDir := filepath.Join(config.Global.WorkDir, vendorDirName)
+		if err := os.RemoveAll(stagingDir); err != nil {
+			return fmt.Errorf("vendor '%v': %w", src, err)
+		}
+		p.vendor.stagingDir = stagingDir
+	}
+	staged := filepath.Join(p.vendor.stagingDir, strconv.Itoa(len(p.vendor.pending)))
+	err := os.MkdirAll(staged, 0755)
+	if err == nil {
+		err = utils.CopyDirectory(dir, staged)
+	}
+	if err == nil {
+		err = os.RemoveAll(filepath.Join(staged, ".git"))
+	}
+	if err != nil {
+		return fmt.Errorf("vendor '%v': %w", src, err)
+	}
+	p.vendor.pending[src] = pendingVendorSource{kind: kind, dir: staged}
+	return nil
+}
+
+// GetRemoteFile returns the file by URL. Vendored copy is used if it exists.
+func (p *Project) GetRemoteFile(URL string) ([]byte, error) {
+	path, vendored, err := p.VendoredSource(URL)
+	if err != nil {
+		return nil, err
+	}
+	if vendored {
+		return os.ReadFile(path)
+	}
+	data, err := utils.GetFileByUrlCached(URL, config.Global.DownloadsCacheDir, config.Global.Offline)
+	if err != nil {
+		return nil, err
+	}
+	if config.Global.Vendoring {
+		p.vendor.pending[URL] = pendingVendorSource{kind: vendoredFileKind, data: data}
+	}
+	return data, nil
+}
+
+// VendoredHelmChart returns the path to the vendored chart archive. During 'cdev vendor' the chart is added
+// to be vendored.
+func (p *Project) VendoredHelmChart(repository, chart, version string) (string, bool, error) {
+	if repository == "" || utils.IsLocalPath(chart) {
+		return "", false, nil
+	}
+	src := fmt.Sprintf("%s/%s@%s", strings.TrimSuffix(repository, "/"), chart, version)
+	if config.Global.Vendoring {
+		if version == "" {
+			log.Warnf("Helm chart '%v' from '%v' is not vendored, set the chart version to vendor it", chart, repository)
+			return "", false, nil
+		}
+		p.vendor.pending[src] = pendingVendorSource{kind: vendoredHelmKind, helm: helmChartRef{repository, chart, version}}
+		return "", false, nil
+	}
+	return p.VendoredSource(src)
+}
+
+// VendoredTerraformModule returns the path to the vendored copy of the terraform module from the registry or other
+// non-git source. During 'cdev vendor' the module is added to be vendored, tfBin is used to download it.
+func (p *Project) VendoredTerraformModule(source, version, tfBin string) (string, bool, error) {
+	src := source
+	if version != "" {
+		src = fmt.Sprintf("%s@%s", source, version)
+	}
+	if config.Global.Vendoring {
+		p.vendor.pending[src] = pendingVendorSource{kind: lockedTfModuleKind, tfModule: tfModuleRef{source, version, tfBin}}
+		return "", false, nil
+	}
+	return p.VendoredSource(src)
+}
+
+func vendoredHash(path string) (string, error) {
+	if utils.IsDir(path) {
+		return utils.DirHash(path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(data)), nil
+}
+
+// Vendor copies all remote sources collected during the project loading to the vendor dir and writes checksums.
+func (p *Project) Vendor() error {
+	vendorDir := filepath.Join(config.Global.WorkingDir, vendorDirName)
+	if p.vendor.stagingDir != "" {
+		defer os.RemoveAll(p.vendor.stagingDir)
+	}
+	if err := os.RemoveAll(vendorDir); err != nil {
+		return err
+	}
+	for src, pending := range p.vendor.pending {
+		name, err := utils.URLToFolderName(src)
+		if err != nil {
+			return err
+		}
+		name = strings.NewReplacer("?", "_", "=", "_").Replace(name)
+		path := filepath.Join(vendorDirName, pending.kind, name)
+		fullPath := filepath.Join(config.Global.WorkingDir, path)
+		switch {
+		case pending.kind == vendoredFileKind:
+			err = os.MkdirAll(filepath.Dir(fullPath), 0755)
+			if err == nil {
+				err = os.WriteFile(fullPath, pending.data, 0644)
+			}
+		case pending.kind == vendoredHelmKind:
+			path, err = vendorHelmChart(pending.helm, path)
+			fullPath = filepath.Join(config.Global.WorkingDir, path)
+		case pending.tfModule.source != "":
+			err = vendorTerraformModule(pending.tfModule, fullPath)
+		default:
+			err = os.MkdirAll(fullPath, 0755)
+			if err == nil {
+				err = utils.CopyDirectory(pending.dir, fullPath)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("vendor '%v': %w", src, err)
+		}
+		hash, err := vendoredHash(fullPath)
+		if err != nil {
+			return fmt.Errorf("vendor '%v': %w", src, err)
+		}
+		p.vendor.Sources[src] = &VendoredSource{
+			Kind: pending.kind,
+			Path: path,
+			Hash: hash,
+		}
+	}
+	if len(p.vendor.Sources) == 0 {
+		log.Info("No remote sources found, nothing to vendor")
+		return nil
+	}
+	buf := bytes.NewBufferString(vendorChecksumsHeader)
+	enc := yaml.NewEncoder(buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(p.vendor); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(vendorDir, vendorChecksumsFileName), buf.Bytes(), 0644)
+}
+
+// vendorHelmChart downloads the chart archive to the dir with 'helm pull'. Returns the path to the archive.
+func vendorHelmChart(ref helmChartRef, dir string) (string, error) {
+	fullDir := filepath.Join(config.Global.WorkingDir, dir)
+	if err := os.MkdirAll(fullDir, 0755); err != nil {
+		return "", err
+	}
+	var command string
+	if strings.HasPrefix(ref.repository, "oci://") {
+		command = fmt.Sprintf("helm pull %s/%s --version %s", strings.TrimSuffix(ref.repository, "/"), ref.chart, ref.version)
+	} else {
+		command = fmt.Sprintf("helm pull %s --repo %s --version %s", ref.chart, ref.repository, ref.version)
+	}
+	var interruptMoc = false
+	shell, err := executor.NewExecutor(fullDir, &interruptMoc)
+	if err != nil {
+		return "", err
+	}
+	_, errOutput, err := shell.RunMutely(command)
+	if err != nil {
+		return "", fmt.Errorf("%v: %v\n%v", command, err.Error(), errOutput)
+	}
+	archives, _ := filepath.Glob(filepath.Join(fullDir, "*.tgz"))
+	if len(archives) != 1 {
+		return "", fmt.Errorf("%v: chart archive not found", command)
+	}
+	return filepath.Join(dir, filepath.Base(archives[0])), nil
+}
+
+// vendorTerraformModule downloads the module with 'terraform get' and copies the module dir to dst. Modules called by
+// the module from remote sources are not vendored.
+func vendorTerraformModule(ref tfModuleRef, dst string) error {
+	tmpDir, err := os.MkdirTemp("", "cdev-vendor-module-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+	f := hclwrite.NewEmptyFile()
+	moduleBody := f.Body().AppendNewBlock("module", []string{"vendor"}).Body()
+	moduleBody.SetAttributeValue("source", cty.StringVal(ref.source))
+	if ref.version != "" {
+		moduleBody.SetAttributeValue("version", cty.StringVal(ref.version))
+	}
+	if err = os.WriteFile(filepath.Join(tmpDir, "main.tf"), f.Bytes(), 0644); err != nil {
+		return err
+	}
+	env := []string{}
+	if config.Global.TerraformCLIConfig != "" {
+		env = append(env, "TF_CLI_CONFIG_FILE="+config.Global.TerraformCLIConfig)
+	}
+	shell, err := executor.NewExecutor(tmpDir, &config.Interrupted, env...)
+	if err != nil {
+		return err
+	}
+	command := fmt.Sprintf("%s get", ref.tfBin)
+	_, errOutput, err := shell.RunMutely(command)
+	if err != nil {
+		return fmt.Errorf("%v: %v\n%v", command, err.Error(), errOutput)
+	}
+	// Terraform writes dirs of downloaded modules to the modules manifest.
+	data, err := os.ReadFile(filepath.Join(tmpDir, ".terraform", "modules", "modules.json"))
+	if err != nil {
+		return fmt.Errorf("%v: read modules manifest: %w", command, err)
+	}
+	manifest := struct {
+		Modules []struct {
+			Key string `json:"Key"`
+			Dir string `json:"Dir"`
+		} `json:"Modules"`
+	}{}
+	if err = json.Unmarshal(data, &manifest); err != nil {
+		return fmt.Errorf("%v: read modules manifest: %w", command, err)
+	}
+	for _, m := range manifest.Modules {
+		if m.Key != "vendor" {
+			continue
+		}
+		if err = os.MkdirAll(dst, 0755); err != nil {
+			return err
+		}
+		if err = utils.CopyDirectory(filepath.Join(tmpDir, m.Dir), dst); err != nil {
+			return err
+		}
+		// Registry modules are often downloaded from git repositories.
+		return os.RemoveAll(filepath.Join(dst, ".git"))
+	}
+	return fmt.Errorf("%v: module dir not found", command)
+}
+
+// PrintVendoredSources prints sources from the vendor dir.
+func (p *Project) PrintVendoredSources() {
+	sources := make([]string, 0, len(p.vendor.Sources))
+	for src := range p.vendor.Sources {
+		sources = append(sources, src)
+	}
+	sort.Strings(sources)
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Source", "Kind", "Path"})
+	for _, src := range sources {
+		table.Append([]string{src, p.vendor.Sources[src].Kind, p.vendor.Sources[src].Path})
+	}
+	table.Render()
+}
@@ -122,7 +122,7 @@ func (u *Unit) LoadState(spec interface{}, modKey string, p *project.StateProjec
 	u.ProjectPtr = &p.Project
 	u.SpecRaw = make(map[string]interface{})
 	//u.UnitMarkers = make(map[string]interface{})
-	u.CacheDir = filepath.Join(u.Project().CodeCacheDir, u.Key())
+	u.CacheDir = filepath.Join(u.Project().CodeCacheDir, project.UnitDirName(u.Key()))
 	u.BackendPtr = &backend
 	err = u.readDeps()
 	if err != nil {
@@ -156,7 +156,7 @@ func (u *Unit) ReadConfig(spec map[string]interface{}, stack *project.Stack) err
 			return fmt.Errorf("read unit: post_hook: %w", err)
 		}
 	}
-	u.CacheDir = filepath.Join(u.Project().CodeCacheDir, u.Key())
+	u.CacheDir = filepath.Join(u.Project().CodeCacheDir, project.UnitDirName(u.Key()))
 	return u.checkShellUnitConfig()
 }
 
@@ -513,12 +513,14 @@ func (u *Unit) readDeps() (err error) {
 			return
 		}
 		infNm := splDep[0]
+		unitName := splDep[1]
 		if infNm == "this" {
 			infNm = u.StackName()
+			unitName = project.UnitNamespace(u.Name()) + unitName
 		}
 		dp := &project.ULinkT{
 			TargetStackName: infNm,
-			TargetUnitName:  splDep[1],
+			TargetUnitName:  unitName,
 			LinkType:        "custom",
 		}
 		u.ProjectPtr.UnitLinks.Set(dp)
@@ -0,0 +1,63 @@
+package helmcli
+
+import (
+	"github.com/apex/log"
+	"github.com/shalb/cluster.dev/internal/project"
+	"github.com/shalb/cluster.dev/internal/units/shell/common"
+)
+
+// Factory factory for helm-cli units.
+type Factory struct {
+}
+
+const unitKind string = "helm-cli"
+
+func NewEmptyUnit() *Unit {
+	unit := Unit{
+		Unit:     *common.NewEmptyUnit(),
+		UnitKind: unitKind,
+	}
+	return &unit
+}
+
+func NewUnit(spec map[string]interface{}, stack *project.Stack) (*Unit, error) {
+	mod := NewEmptyUnit()
+
+	cUnit, err := common.NewUnit(spec, stack)
+	if err != nil {
+		log.Debug(err.Error())
+		return nil, err
+	}
+	mod.Unit = *cUnit
+	err = mod.ReadConfig(spec, stack)
+	if err != nil {
+		log.Debug(err.Error())
+		return nil, err
+	}
+	mod.BackendName = stack.BackendName
+	return mod, nil
+}
+
+// New creates new unit driver factory.
+func (f *Factory) New(spec map[string]interface{}, stack *project.Stack) (project.Unit, error) {
+	return NewUnit(spec, stack)
+}
+
+// NewFromState creates new unit from state data.
+func (f *Factory) NewFromState(spec map[string]interface{}, modKey string, p *project.StateProject) (project.Unit, error) {
+	mod := NewEmptyUnit()
+	err := mod.LoadState(spec, modKey, p)
+	if err != nil {
+		log.Debug(err.Error())
+		return nil, err
+	}
+	return mod, nil
+}
+
+func init() {
+	modDrv := Factory{}
+	log.Debugf("Registering unit driver '%v'", unitKind)
+	if err := project.RegisterUnitFactory(&modDrv, unitKind); err != nil {
+		log.Fatalf("Can't register unit driver '%v'.", unitKind)
+	}
+}
@@ -0,0 +1,68 @@
+package helmcli
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/apex/log"
+	"github.com/shalb/cluster.dev/internal/config"
+	"github.com/shalb/cluster.dev/internal/project"
+	"github.com/shalb/cluster.dev/pkg/executor"
+	"github.com/shalb/cluster.dev/pkg/utils"
+)
+
+// helmDiffHeader matches object headers of 'helm diff' output, e.g. 'default, web, Deployment (apps) has changed:'.
+var helmDiffHeader = regexp.MustCompile(`^(\S+), (\S+), (.+) has (been added|been removed|changed):$`)
+
+// Plan compares the release with the chart and values using 'helm diff upgrade', if the helm-diff plugin is
+// installed. Otherwise the live plan is skipped.
+func (u *Unit) Plan() error {
+	u.SetLivePlan(nil)
+	rn, err := executor.NewExecutor(u.CacheDir, &config.Interrupted)
+	if err != nil {
+		return fmt.Errorf("plan unit '%v': %w", u.Key(), err)
+	}
+	plugins, _, err := rn.RunMutely(fmt.Sprintf("%s plugin list", helmBin))
+	if err != nil || !hasDiffPlugin(plugins) {
+		log.Warnf("Unit '%v': helm-diff plugin is not installed, live plan skipped. Install it with 'helm plugin install https://github.com/databus23/helm-diff'", u.Key())
+		return nil
+	}
+	cmd := fmt.Sprintf("%s diff upgrade %s %s %s --allow-unreleased --no-color", helmBin, utils.ShellQuote(u.releaseName()), u.chartRef(), u.upgradeOpts())
+	out, errMsg, err := rn.RunMutely(cmd)
+	if err != nil {
+		return fmt.Errorf("plan unit '%v': %w, error output:\n %v", u.Key(), err, errMsg)
+	}
+	u.SetLivePlan(parseHelmDiff(out))
+	return nil
+}
+
+func hasDiffPlugin(pluginsList string) bool {
+	for _, line := range strings.Split(pluginsList, "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 && fields[0] == "diff" {
+			return true
+		}
+	}
+	return false
+}
+
+// parseHelmDiff reads changed objects from 'helm diff' output.
+func parseHelmDiff(out string) *project.LivePlan {
+	res := &project.LivePlan{Diff: out}
+	for _, line := range strings.Split(out, "\n") {
+		parsed := helmDiffHeader.FindStringSubmatch(strings.TrimSpace(line))
+		if parsed == nil {
+			continue
+		}
+		obj := fmt.Sprintf("%s %s/%s", parsed[3], parsed[1], parsed[2])
+		switch parsed[4] {
+		case "been added":
+			res.Created = append(res.Created, obj)
+		case "been removed":
+			res.Deleted = append(res.Deleted, obj)
+		default:
+			res.Changed = append(res.Changed, obj)
+		}
+	}
+	return res
+}
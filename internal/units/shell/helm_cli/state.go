@@ -0,0 +1,147 @@
+package helmcli
+
+import (
+	"fmt"
+
+	"github.com/apex/log"
+	"github.com/shalb/cluster.dev/internal/project"
+	"github.com/shalb/cluster.dev/internal/units/shell/common"
+	"github.com/shalb/cluster.dev/pkg/utils"
+)
+
+const outputsParserName = "helm"
+
+type UnitDiffSpec struct {
+	common.UnitDiffSpec
+	Source          SourceSpec             `json:"source"`
+	ReleaseName     string                 `json:"release_name"`
+	Namespace       string                 `json:"namespace,omitempty"`
+	CreateNamespace bool                   `json:"create_namespace,omitempty"`
+	Kubeconfig      *string                `json:"kubeconfig,omitempty"`
+	HelmOpts        string                 `json:"helm_opts,omitempty"`
+	Sets            map[string]interface{} `json:"inputs,omitempty"`
+	Values          []interface{}          `json:"values,omitempty"`
+}
+
+// helmStatus is the part of 'helm status -o json' output used for outputs.
+type helmStatus struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Version   int    `json:"version"`
+	Info      struct {
+		Status string `json:"status"`
+		Notes  string `json:"notes"`
+	} `json:"info"`
+	Chart struct {
+		Metadata struct {
+			Version    string `json:"version"`
+			AppVersion string `json:"appVersion"`
+		} `json:"metadata"`
+	} `json:"chart"`
+}
+
+// helmStatusParser reads the release status and sets unit outputs: name, namespace, revision, status, notes,
+// chart_version and app_version.
+func (u *Unit) helmStatusParser(in string, out *project.UnitLinksT) error {
+	status := helmStatus{}
+	if err := utils.JSONDecode([]byte(in), &status); err != nil {
+		return fmt.Errorf("read release status: %w", err)
+	}
+	u.Revision = status.Version
+	outputs := map[string]interface{}{
+		"name":          status.Name,
+		"namespace":     status.Namespace,
+		"revision":      status.Version,
+		"status":        status.Info.Status,
+		"notes":         status.Info.Notes,
+		"chart_version": status.Chart.Metadata.Version,
+		"app_version":   status.Chart.Metadata.AppVersion,
+	}
+	if out == nil {
+		return nil
+	}
+	for _, expOutput := range out.Map() {
+		data, exists := outputs[expOutput.OutputName]
+		if !exists {
+			return fmt.Errorf("unit has no output named '%v', expected by another unit", expOutput.OutputName)
+		}
+		expOutput.OutputData = data
+	}
+	return nil
+}
+
+func (u *Unit) GetState() project.Unit {
+	if u.SavedState != nil {
+		return u.SavedState
+	}
+	unitState := Unit{}
+	err := utils.JSONCopy(u, &unitState)
+	if err != nil {
+		log.Fatalf("read unit '%v': create state: %w", u.Name(), err)
+	}
+	unitState.Unit = *u.Unit.GetStateUnit()
+	unitState.ApplyConf = nil
+	unitState.DestroyConf = nil
+	unitState.InitConf = nil
+	unitState.PlanConf = nil
+	unitState.GetOutputsConf = nil
+	unitState.Env = nil
+	unitState.OutputParsers = nil
+	unitState.CreateFiles = nil
+	unitState.WorkDir = ""
+	return &unitState
+}
+
+func (u *Unit) GetUnitDiff() UnitDiffSpec {
+	diff := u.Unit.GetUnitDiff()
+	st := UnitDiffSpec{
+		UnitDiffSpec:    diff,
+		Source:          u.Source,
+		ReleaseName:     u.releaseName(),
+		Namespace:       u.Namespace,
+		CreateNamespace: u.CreateNamespace,
+		Kubeconfig:      u.Kubeconfig,
+		HelmOpts:        u.HelmOpts,
+		Sets:            u.Sets,
+	}
+	// Show values as data, not as strings, so the diff shows changed keys.
+	for _, values := range u.ValuesFilesList {
+		data, err := utils.ReadYAML([]byte(values))
+		if err != nil {
+			st.Values = append(st.Values, values)
+			continue
+		}
+		st.Values = append(st.Values, data)
+	}
+	st.UnitDiffSpec.ApplyConf = nil
+	st.UnitDiffSpec.OutputsConfig = nil
+	st.UnitDiffSpec.CreateFilesDiff = nil
+	st.UnitDiffSpec.Env = nil
+	return st
+}
+
+func (u *Unit) GetDiffData() interface{} {
+	diff := u.GetUnitDiff()
+	diffData := map[string]interface{}{}
+	utils.JSONCopy(diff, &diffData)
+	project.ScanMarkers(&diffData, project.StateOutputsReplacer, u)
+	u.ReplaceOutputsForDiff(diffData, &diffData)
+	return diffData
+}
+
+func (u *Unit) GetStateDiffData() interface{} {
+	return ""
+}
+
+func (u *Unit) LoadState(spec interface{}, modKey string, p *project.StateProject) error {
+	err := u.Unit.LoadState(spec, modKey, p)
+	if err != nil {
+		return err
+	}
+	err = utils.JSONCopy(spec, &u)
+	if err != nil {
+		return fmt.Errorf("loading unit state: can't parse state: %v", err.Error())
+	}
+	u.fillShellUnit()
+	return nil
+}
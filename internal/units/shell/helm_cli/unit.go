@@ -0,0 +1,273 @@
+package helmcli
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/shalb/cluster.dev/internal/config"
+	"github.com/shalb/cluster.dev/internal/project"
+	"github.com/shalb/cluster.dev/internal/units/shell/common"
+	"github.com/shalb/cluster.dev/pkg/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// SourceSpec describes the chart source.
+type SourceSpec struct {
+	Repository string `yaml:"repository,omitempty" json:"repository,omitempty"`
+	Chart      string `yaml:"chart" json:"chart"`
+	Version    string `yaml:"version,omitempty" json:"version,omitempty"`
+}
+
+// Unit installs the Helm chart with helm CLI.
+type Unit struct {
+	common.Unit
+	project.LivePlanResult `yaml:"-" json:"-"`
+
+	Source          SourceSpec             `yaml:"source" json:"source"`
+	ReleaseName     string                 `yaml:"release_name,omitempty" json:"release_name,omitempty"`
+	Namespace       string                 `yaml:"namespace,omitempty" json:"namespace,omitempty"`
+	CreateNamespace bool                   `yaml:"create_namespace,omitempty" json:"create_namespace,omitempty"`
+	Kubeconfig      *string                `yaml:"kubeconfig,omitempty" json:"kubeconfig,omitempty"`
+	HelmOpts        string                 `yaml:"helm_opts,omitempty" json:"helm_opts,omitempty"`
+	Sets            map[string]interface{} `yaml:"inputs,omitempty" json:"inputs,omitempty"`
+	// ValuesFilesList contains rendered values files, merged in order as helm does with multiple -f options.
+	ValuesFilesList []string `yaml:"-" json:"values,omitempty"`
+	// Revision is the release revision after the last apply, it doesn't affect the plan.
+	Revision int    `yaml:"-" json:"revision,omitempty"`
+	UnitKind string `yaml:"-" json:"type"`
+}
+
+var helmBin = "helm"
+
+const valuesDirName = "values"
+
+func (u *Unit) KindKey() string {
+	return unitKind
+}
+
+// maxReleaseNameLen is the max length of the release name allowed by helm.
+const maxReleaseNameLen = 53
+
+var (
+	releaseNameRe        = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+	releaseNameInvalidRe = regexp.MustCompile(`[^a-z0-9-]+`)
+)
+
+// releaseName returns the release name. By default the unit name is converted to the DNS-1123 label,
+// e.g. 'app-prod' for 'app[prod]' and 'addons-ingress' for 'addons:ingress'.
+func (u *Unit) releaseName() string {
+	if u.ReleaseName != "" {
+		return u.ReleaseName
+	}
+	return strings.Trim(releaseNameInvalidRe.ReplaceAllString(strings.ToLower(u.Name()), "-"), "-")
+}
+
+// chartRef returns the chart reference for helm CLI: the chart name with --repo option, OCI reference or the local
+// chart path.
+func (u *Unit) chartRef() string {
+	ref := u.Source.Chart
+	if u.Source.Repository != "" {
+		ref = fmt.Sprintf("%s --repo %s", ref, u.Source.Repository)
+	}
+	if u.Source.Version != "" {
+		ref = fmt.Sprintf("%s --version %s", ref, u.Source.Version)
+	}
+	return ref
+}
+
+// helmOpts returns common helm options: namespace and kubeconfig.
+func (u *Unit) helmOpts() string {
+	opts := ""
+	if u.Namespace != "" {
+		opts += "-n " + u.Namespace
+	}
+	if u.Kubeconfig != nil && *u.Kubeconfig != "" {
+		opts = fmt.Sprintf("%s --kubeconfig='%s'", opts, *u.Kubeconfig)
+	}
+	return opts
+}
+
+// upgradeOpts returns options of 'helm upgrade' and 'helm diff upgrade': values files, sets and custom options.
+func (u *Unit) upgradeOpts() string {
+	opts := u.helmOpts()
+	for i := range u.ValuesFilesList {
+		opts = fmt.Sprintf("%s -f %s", opts, filepath.Join(u.CacheDir, valuesDirName, valuesFileName(i)))
+	}
+	opts += utils.FlagArgs("--set", u.Sets)
+	if u.HelmOpts != "" {
+		opts = fmt.Sprintf("%s %s", opts, u.HelmOpts)
+	}
+	return opts
+}
+
+func valuesFileName(i int) string {
+	return fmt.Sprintf("%02d.yaml", i)
+}
+
+func (u *Unit) fillShellUnit() {
+	createNS := ""
+	if u.CreateNamespace {
+		createNS = " --create-namespace"
+	}
+	u.ApplyConf = &common.OperationConfig{
+		Commands: []interface{}{
+			fmt.Sprintf("%s upgrade --install %s %s %s%s", helmBin, utils.ShellQuote(u.releaseName()), u.chartRef(), u.upgradeOpts(), createNS),
+		},
+	}
+	u.DestroyConf = &common.OperationConfig{
+		Commands: []interface{}{
+			fmt.Sprintf("%s uninstall %s %s", helmBin, utils.ShellQuote(u.releaseName()), u.helmOpts()),
+		},
+	}
+	u.GetOutputsConf = &common.OutputsConfigSpec{
+		Command: fmt.Sprintf("%s status %s %s -o json", helmBin, utils.ShellQuote(u.releaseName()), u.helmOpts()),
+		Type:    outputsParserName,
+	}
+	u.OutputParsers[outputsParserName] = u.helmStatusParser
+}
+
+func (u *Unit) ReadConfig(spec map[string]interface{}, stack *project.Stack) error {
+	err := utils.YAMLInterfaceToType(spec, u)
+	if err != nil {
+		return err
+	}
+	if u.Source.Chart == "" {
+		return fmt.Errorf("read unit '%v': option 'source.chart' is required", u.Name())
+	}
+	// Local chart path is relative to the stack template dir.
+	if u.Source.Repository == "" && (strings.HasPrefix(u.Source.Chart, "./") || strings.HasPrefix(u.Source.Chart, "../")) {
+		u.Source.Chart = filepath.Join(config.Global.WorkingDir, u.StackPtr.TemplateDir, u.Source.Chart)
+	}
+	if err = u.readValues(spec["values"]); err != nil {
+		return fmt.Errorf("read unit '%v': %w", u.Name(), err)
+	}
+	if name := u.releaseName(); len(name) > maxReleaseNameLen || !releaseNameRe.MatchString(name) {
+		return fmt.Errorf("read unit '%v': invalid release name '%v', it must be a lowercase DNS-1123 label up to %v characters", u.Name(), name, maxReleaseNameLen)
+	}
+	u.UnitKind = u.KindKey()
+	u.CacheDir = filepath.Join(u.Project().CodeCacheDir, project.UnitDirName(u.Key()))
+	return nil
+}
+
+// readValues reads values in the same format as helm unit: list of values files or values sets.
+func (u *Unit) readValues(spec interface{}) error {
+	if spec == nil {
+		return nil
+	}
+	valuesList, ok := spec.([]interface{})
+	if !ok {
+		return fmt.Errorf("'values' have unknown format: %v", reflect.TypeOf(spec))
+	}
+	for _, v := range valuesList {
+		valuesMap, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("'values' have unknown format: %v", reflect.TypeOf(v))
+		}
+		applyTemplate, exists := valuesMap["apply_template"].(bool)
+		if !exists {
+			applyTemplate = true
+		}
+		fileName, ok := valuesMap["file"].(string)
+		if !ok {
+			setData, ok := valuesMap["set"].(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("one of 'values.file' or 'values.set' required")
+			}
+			data, err := yaml.Marshal(setData)
+			if err != nil {
+				return err
+			}
+			u.ValuesFilesList = append(u.ValuesFilesList, string(data))
+			continue
+		}
+		path := filepath.Join(config.Global.WorkingDir, u.StackPtr.TemplateDir, fileName)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("can't load values file: %w", err)
+		}
+		if applyTemplate {
+			rendered, errIsWarn, err := u.Stack().TemplateTry(data, path)
+			if err != nil && !errIsWarn {
+				return err
+			}
+			data = rendered
+		}
+		check := map[string]interface{}{}
+		if err = yaml.Unmarshal(data, &check); err != nil {
+			return fmt.Errorf("unmarshal values file: %v", utils.ResolveYamlError(data, err))
+		}
+		u.ValuesFilesList = append(u.ValuesFilesList, string(data))
+	}
+	return nil
+}
+
+// ScanData scan all markers in unit, and build project unit links, and unit dependencies.
+func (u *Unit) ScanData(scanner project.MarkerScanner) error {
+	if err := u.Unit.ScanData(scanner); err != nil {
+		return err
+	}
+	for _, data := range []interface{}{u.ValuesFilesList, u.Sets, u.Kubeconfig} {
+		if err := project.ScanMarkers(data, scanner, u); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Prepare scan all markers in unit, and build project unit links, and unit dependencies.
+func (u *Unit) Prepare() error {
+	err := u.Unit.Prepare()
+	if err != nil {
+		return err
+	}
+	return u.ScanData(project.OutputsScanner)
+}
+
+func (u *Unit) Build() error {
+	// Save state before output markers replace.
+	u.SavedState = u.GetState()
+	err := u.ScanData(project.OutputsReplacer)
+	if err != nil {
+		return err
+	}
+	for i, values := range u.ValuesFilesList {
+		if err = u.CreateFiles.AddOverride(filepath.Join(valuesDirName, valuesFileName(i)), values, fs.ModePerm); err != nil {
+			return err
+		}
+	}
+	u.fillShellUnit()
+	return u.Unit.Build()
+}
+
+// Init unit.
+func (u *Unit) Init() error {
+	return nil
+}
+
+// Apply unit.
+func (u *Unit) Apply() error {
+	err := u.Unit.Apply()
+	if err != nil {
+		return err
+	}
+	if savedState, ok := u.SavedState.(*Unit); ok {
+		savedState.Revision = u.Revision
+	}
+	u.CreateFiles = nil
+	return nil
+}
+
+// Destroy unit.
+func (u *Unit) Destroy() error {
+	err := u.Unit.Destroy()
+	if err != nil {
+		return err
+	}
+	u.CreateFiles = nil
+	return nil
+}
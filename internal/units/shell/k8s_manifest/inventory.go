@@ -0,0 +1,115 @@
+package base
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/shalb/cluster.dev/pkg/utils"
+)
+
+// InventoryItem identifies k8s object applied by the unit.
+type InventoryItem struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+}
+
+// group returns the API group of the object, empty for the core group.
+func (i InventoryItem) group() string {
+	if group, _, found := strings.Cut(i.APIVersion, "/"); found {
+		return group
+	}
+	return ""
+}
+
+// key identifies the object regardless of the API version, so version upgrades of the manifest don't prune it.
+func (i InventoryItem) key() string {
+	return fmt.Sprintf("%s.%s.%s.%s", i.Kind, i.group(), i.Namespace, i.Name)
+}
+
+// String returns the object reference for plan output, e.g. 'apps/v1 Deployment ns/name'.
+func (i InventoryItem) String() string {
+	name := i.Name
+	if i.Namespace != "" {
+		name = i.Namespace + "/" + name
+	}
+	return fmt.Sprintf("%s %s %s", i.APIVersion, i.Kind, name)
+}
+
+// resource returns the object reference for kubectl in the format 'Kind.version.group/name'.
+func (i InventoryItem) resource() string {
+	group, version, found := strings.Cut(i.APIVersion, "/")
+	if !found {
+		return fmt.Sprintf("%s/%s", i.Kind, i.Name)
+	}
+	return fmt.Sprintf("%s.%s.%s/%s", i.Kind, version, group, i.Name)
+}
+
+// inventory returns objects rendered from the unit manifests, sorted by key.
+func (u *Unit) inventory() []InventoryItem {
+	res := []InventoryItem{}
+	if u == nil || u.ManifestsFiles == nil {
+		return res
+	}
+	for _, file := range *u.ManifestsFiles {
+		objs, err := utils.ReadYAMLObjects([]byte(file.Content))
+		if err != nil {
+			continue
+		}
+		for _, obj := range objs {
+			item := InventoryItem{}
+			item.APIVersion, _ = obj["apiVersion"].(string)
+			item.Kind, _ = obj["kind"].(string)
+			if metadata, ok := obj["metadata"].(map[string]interface{}); ok {
+				item.Name, _ = metadata["name"].(string)
+				item.Namespace, _ = metadata["namespace"].(string)
+			}
+			if item.Kind == "" || item.Name == "" {
+				continue
+			}
+			res = append(res, item)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].key() < res[j].key()
+	})
+	return res
+}
+
+// pruneInventory returns objects from the unit state inventory which are not rendered anymore. Namespaced objects
+// are returned first, namespaces and CRDs last, so objects are deleted before their namespaces and definitions.
+func (u *Unit) pruneInventory() []InventoryItem {
+	if u.ProjectPtr == nil || u.ProjectPtr.OwnState == nil {
+		return nil
+	}
+	stateUnit, exists := u.ProjectPtr.OwnState.Units[u.Key()]
+	if !exists {
+		return nil
+	}
+	stateK8sUnit, ok := stateUnit.(*Unit)
+	if !ok {
+		return nil
+	}
+	stateInventory := stateK8sUnit.Inventory
+	if len(stateInventory) == 0 {
+		// State of older versions has no inventory, use manifests from the state.
+		stateInventory = stateK8sUnit.inventory()
+	}
+	rendered := map[string]bool{}
+	for _, item := range u.inventory() {
+		rendered[item.key()] = true
+	}
+	res := []InventoryItem{}
+	for _, item := range stateInventory {
+		if !rendered[item.key()] {
+			res = append(res, item)
+		}
+	}
+	deleteLast := map[string]bool{"Namespace": true, "CustomResourceDefinition": true}
+	sort.SliceStable(res, func(i, j int) bool {
+		return !deleteLast[res[i].Kind] && deleteLast[res[j].Kind]
+	})
+	return res
+}
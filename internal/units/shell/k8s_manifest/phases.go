@@ -0,0 +1,108 @@
+package base
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/shalb/cluster.dev/pkg/utils"
+	"gopkg.in/yaml.v3"
+)
+
+const phasesDirName = "phases"
+
+// applyPhase is a group of objects applied together. Phases are applied in order and deleted in reverse order.
+type applyPhase struct {
+	name    string
+	kinds   map[string]bool
+	objects []map[string]interface{}
+}
+
+// newApplyPhases returns phases in apply order: namespaces and CRDs, RBAC, other objects (workloads, custom
+// resources, etc.).
+func newApplyPhases() []*applyPhase {
+	return []*applyPhase{
+		{name: "cluster", kinds: map[string]bool{"Namespace": true, "CustomResourceDefinition": true}},
+		{name: "rbac", kinds: map[string]bool{"ServiceAccount": true, "Role": true, "ClusterRole": true, "RoleBinding": true, "ClusterRoleBinding": true}},
+		{name: "resources"},
+	}
+}
+
+func (p *applyPhase) fileName() string {
+	return fmt.Sprintf("%s.yaml", p.name)
+}
+
+// applyPhases splits objects rendered from manifests by phases. Empty phases are omitted.
+func (u *Unit) applyPhases() []*applyPhase {
+	phases := newApplyPhases()
+	if u.ManifestsFiles == nil {
+		return nil
+	}
+	for _, file := range *u.ManifestsFiles {
+		objs, err := utils.ReadYAMLObjects([]byte(file.Content))
+		if err != nil {
+			continue
+		}
+		for _, obj := range objs {
+			kind, _ := obj["kind"].(string)
+			for _, phase := range phases {
+				if phase.kinds == nil || phase.kinds[kind] {
+					phase.objects = append(phase.objects, obj)
+					break
+				}
+			}
+		}
+	}
+	res := []*applyPhase{}
+	for i, phase := range phases {
+		if len(phase.objects) > 0 {
+			phase.name = fmt.Sprintf("%02d-%s", i, phase.name)
+			res = append(res, phase)
+		}
+	}
+	return res
+}
+
+// writePhases writes objects of each phase to a separate file in the phases dir.
+func (u *Unit) writePhases() error {
+	dir := filepath.Join(u.CacheDir, phasesDirName)
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, phase := range u.applyPhases() {
+		var data []byte
+		for i, obj := range phase.objects {
+			if i != 0 {
+				data = append(data, []byte("---\n")...)
+			}
+			out, err := yaml.Marshal(obj)
+			if err != nil {
+				return err
+			}
+			data = append(data, out...)
+		}
+		if err := os.WriteFile(filepath.Join(dir, phase.fileName()), data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// phaseCRDs returns kubectl references of CRDs in the phase.
+func phaseCRDs(phase *applyPhase) []string {
+	res := []string{}
+	for _, obj := range phase.objects {
+		if kind, _ := obj["kind"].(string); kind != "CustomResourceDefinition" {
+			continue
+		}
+		if metadata, ok := obj["metadata"].(map[string]interface{}); ok {
+			if name, _ := metadata["name"].(string); name != "" {
+				res = append(res, "crd/"+name)
+			}
+		}
+	}
+	return res
+}
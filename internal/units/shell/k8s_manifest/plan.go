@@ -0,0 +1,71 @@
+package base
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/shalb/cluster.dev/internal/config"
+	"github.com/shalb/cluster.dev/internal/project"
+	"github.com/shalb/cluster.dev/pkg/executor"
+)
+
+// Plan compares rendered manifests with live objects using server-side dry-run 'kubectl diff'. Objects from the
+// prune list are planned as deleted.
+func (u *Unit) Plan() error {
+	rn, err := executor.NewExecutor(u.CacheDir, &config.Interrupted)
+	if err != nil {
+		return fmt.Errorf("plan unit '%v': %w", u.Key(), err)
+	}
+	diffOpts := u.applyOpts()
+	if diffOpts == "" {
+		diffOpts = "--server-side"
+	}
+	cmd := fmt.Sprintf("%s diff %s -R %s -f %s", kubectlBin, diffOpts, u.kubectlOpts(), filepath.Join(u.CacheDir, "workdir"))
+	out, errMsg, err := rn.RunMutely(cmd)
+	// kubectl diff exits with 1 if differences are found, other codes mean an error.
+	var exitErr *exec.ExitError
+	if err != nil && !(errors.As(err, &exitErr) && exitErr.ExitCode() == 1) {
+		return fmt.Errorf("plan unit '%v': %w, error output:\n %v", u.Key(), err, errMsg)
+	}
+	livePlan := parseKubectlDiff(out)
+	for _, item := range u.pruneList {
+		livePlan.Deleted = append(livePlan.Deleted, item.String())
+	}
+	u.SetLivePlan(livePlan)
+	return nil
+}
+
+// parseKubectlDiff reads objects from 'kubectl diff' output. Each object diff starts with the line
+// 'diff -u -N <LIVE dir>/<group.version.Kind.namespace.name> <MERGED dir>/<...>', objects without the live part
+// will be created.
+func parseKubectlDiff(out string) *project.LivePlan {
+	res := &project.LivePlan{Diff: out}
+	var current string
+	created := false
+	flush := func() {
+		if current == "" {
+			return
+		}
+		if created {
+			res.Created = append(res.Created, current)
+		} else {
+			res.Changed = append(res.Changed, current)
+		}
+	}
+	for _, line := range strings.Split(out, "\n") {
+		switch {
+		case strings.HasPrefix(line, "diff "):
+			flush()
+			fields := strings.Fields(line)
+			current = filepath.Base(fields[len(fields)-1])
+			created = false
+		case strings.HasPrefix(line, "@@ -0,0 "):
+			created = true
+		}
+	}
+	flush()
+	return res
+}
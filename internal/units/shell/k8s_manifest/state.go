@@ -34,6 +34,7 @@ func (u *Unit) GetState() project.Unit {
 	unitState.OutputParsers = nil
 	unitState.CreateFiles = nil
 	unitState.WorkDir = ""
+	unitState.Inventory = u.inventory()
 	return &unitState
 }
 
@@ -58,6 +59,14 @@ func (u *Unit) GetDiffData() interface{} {
 	utils.JSONCopy(diff, &diffData)
 	project.ScanMarkers(&diffData, project.StateOutputsReplacer, u)
 	u.ReplaceOutputsForDiff(diffData, &diffData)
+	// Show objects removed from manifests explicitly, they are deleted from the cluster on apply.
+	if pruneList := u.pruneInventory(); len(pruneList) > 0 {
+		prune := []string{}
+		for _, item := range pruneList {
+			prune = append(prune, item.String())
+		}
+		diffData["prune"] = prune
+	}
 	return diffData
 
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io/fs"
 	"path/filepath"
+	"strings"
 
 	"github.com/apex/log"
 	"github.com/shalb/cluster.dev/internal/config"
@@ -26,56 +27,96 @@ type KubectlCliT struct {
 // Unit describe cluster.dev unit to deploy/destroy k8s resources with kubectl.
 type Unit struct {
 	common.Unit
-	Namespace          string             `yaml:"namespace,omitempty" json:"namespace,omitempty"`
-	Kubeconfig         *string            `yaml:"kubeconfig,omitempty" json:"kubeconfig,omitempty"`
-	KubectlOpts        string             `yaml:"kubectl_opts,omitempty" json:"kubectl_opts,omitempty"`
-	KubectlCliConf     *KubectlCliT       `yaml:"kubectl,omitempty" json:"kubectl,omitempty"`
-	Path               string             `yaml:"path" json:"path"`
-	ManifestsFiles     *common.FilesListT `yaml:"-" json:"manifests"`
-	ApplyTemplate      bool               `yaml:"apply_template" json:"-"`
-	recursive          bool               `yaml:"-" json:"-"`
-	UnitKind           string             `yaml:"-" json:"type"`
-	manifestsForDelete *common.FilesListT `yaml:"-" json:"-"`
-	CreateNamespaces   bool               `yaml:"create_namespaces" json:"-"`
-	createNSList       []string           `yaml:"-" json:"-"`
+	project.LivePlanResult `yaml:"-" json:"-"`
+
+	Namespace        string             `yaml:"namespace,omitempty" json:"namespace,omitempty"`
+	Kubeconfig       *string            `yaml:"kubeconfig,omitempty" json:"kubeconfig,omitempty"`
+	KubectlOpts      string             `yaml:"kubectl_opts,omitempty" json:"kubectl_opts,omitempty"`
+	KubectlCliConf   *KubectlCliT       `yaml:"kubectl,omitempty" json:"kubectl,omitempty"`
+	Path             string             `yaml:"path" json:"path"`
+	ManifestsFiles   *common.FilesListT `yaml:"-" json:"manifests"`
+	ApplyTemplate    bool               `yaml:"apply_template" json:"-"`
+	recursive        bool               `yaml:"-" json:"-"`
+	UnitKind         string             `yaml:"-" json:"type"`
+	CreateNamespaces bool               `yaml:"create_namespaces" json:"-"`
+	createNSList     []string           `yaml:"-" json:"-"`
+	// Inventory contains objects applied by the unit, saved in the state to prune objects removed from manifests.
+	Inventory []InventoryItem `yaml:"-" json:"inventory,omitempty"`
+	pruneList []InventoryItem `yaml:"-" json:"-"`
+	// Wait describes readiness checks after apply.
+	Wait           *WaitSpecT `yaml:"wait,omitempty" json:"wait,omitempty"`
+	ServerSide     bool       `yaml:"server_side" json:"server_side,omitempty"`
+	FieldManager   string     `yaml:"field_manager,omitempty" json:"field_manager,omitempty"`
+	ForceConflicts bool       `yaml:"force_conflicts" json:"force_conflicts,omitempty"`
 }
 
+const defaultFieldManager = "cdev"
+
 var kubectlBin = "kubectl"
 
 func (u *Unit) KindKey() string {
 	return unitKind
 }
 
-func (u *Unit) fillShellUnit() {
-	commandOpts := "-R "
-	// if u.recursive {
-	// 	commandOpts += "-R "
-	// }
+// kubectlOpts returns common kubectl options of the unit: namespace, custom options and kubeconfig.
+func (u *Unit) kubectlOpts() string {
+	opts := ""
 	if u.Namespace != "" {
-		commandOpts += "-n " + u.Namespace
+		opts += "-n " + u.Namespace
 	}
 	if u.KubectlOpts != "" {
-		commandOpts = fmt.Sprintf("%s %s", commandOpts, u.KubectlOpts)
+		opts = fmt.Sprintf("%s %s", opts, u.KubectlOpts)
 	}
 	if u.Kubeconfig != nil && *u.Kubeconfig != "" {
-		commandOpts = fmt.Sprintf("%s --kubeconfig='%s'", commandOpts, *u.Kubeconfig)
+		opts = fmt.Sprintf("%s --kubeconfig='%s'", opts, *u.Kubeconfig)
+	}
+	return opts
+}
+
+// applyOpts returns server-side apply options, empty for client-side apply.
+func (u *Unit) applyOpts() string {
+	if !u.ServerSide {
+		return ""
+	}
+	fieldManager := u.FieldManager
+	if fieldManager == "" {
+		fieldManager = defaultFieldManager
+	}
+	opts := fmt.Sprintf("--server-side --field-manager=%s", fieldManager)
+	if u.ForceConflicts {
+		opts += " --force-conflicts"
 	}
-	if u.manifestsForDelete != nil {
-		u.ApplyConf = &common.OperationConfig{
-			Commands: []interface{}{
-				fmt.Sprintf("%s delete %s -f %s", kubectlBin, commandOpts, filepath.Join(u.CacheDir, "delete/main.yaml")),
-			},
+	return opts
+}
+
+func (u *Unit) fillShellUnit() {
+	phasesDir := filepath.Join(u.CacheDir, phasesDirName)
+	phases := u.applyPhases()
+	u.ApplyConf = &common.OperationConfig{}
+	for i, phase := range phases {
+		u.ApplyConf.Commands = append(u.ApplyConf.Commands, fmt.Sprintf("%s apply %s %s -f %s", kubectlBin, u.applyOpts(), u.kubectlOpts(), filepath.Join(phasesDir, phase.fileName())))
+		// Custom resources of the next phases can't be applied until their CRDs are established.
+		if crds := phaseCRDs(phase); len(crds) > 0 && i < len(phases)-1 {
+			u.ApplyConf.Commands = append(u.ApplyConf.Commands, fmt.Sprintf("%s wait %s --for=condition=Established --timeout=%s %s", kubectlBin, u.kubectlOpts(), u.waitTimeout(), strings.Join(crds, " ")))
 		}
-	} else {
-		u.ApplyConf = &common.OperationConfig{}
 	}
-	u.ApplyConf.Commands = append(u.ApplyConf.Commands, fmt.Sprintf("%s apply %s -f %s", kubectlBin, commandOpts, filepath.Join(u.CacheDir, "workdir")))
+	for _, cmd := range u.waitCommands() {
+		u.ApplyConf.Commands = append(u.ApplyConf.Commands, cmd)
+	}
+	// Prune objects removed from manifests after apply, so replacing objects are created first.
+	for _, item := range u.pruneList {
+		pruneOpts := u.kubectlOpts()
+		if item.Namespace != "" {
+			pruneOpts = fmt.Sprintf("%s -n %s", pruneOpts, item.Namespace)
+		}
+		u.ApplyConf.Commands = append(u.ApplyConf.Commands, fmt.Sprintf("%s delete %s --ignore-not-found %s", kubectlBin, pruneOpts, item.resource()))
+	}
 
 	// log.Warnf("path: %v", u.Path)
-	u.DestroyConf = &common.OperationConfig{
-		Commands: []interface{}{
-			fmt.Sprintf("%s delete %s -R -f %s", kubectlBin, commandOpts, filepath.Join(u.CacheDir, "workdir")),
-		},
+	// Delete in reverse order, so custom resources are deleted before their CRDs and objects before namespaces.
+	u.DestroyConf = &common.OperationConfig{}
+	for i := len(phases) - 1; i >= 0; i-- {
+		u.DestroyConf.Commands = append(u.DestroyConf.Commands, fmt.Sprintf("%s delete %s -f %s", kubectlBin, u.kubectlOpts(), filepath.Join(phasesDir, phases[i].fileName())))
 	}
 	// u.CreateFiles = nil
 	// log.Warnf("apply: %+v", u.ApplyConf)
@@ -137,11 +178,11 @@ func (u *Unit) ReadManifestsPath(src string) error {
 		log.Debugf("Template dir: %v", manifestsPath)
 
 	} else {
-		manifest, err := utils.GetFileByUrl(src)
+		manifest, err := u.ProjectPtr.GetRemoteFile(src)
 		if err != nil {
 			return fmt.Errorf("get remote file by url (%v): %w", src, err)
 		}
-		err = u.ManifestsFiles.AddOverride("./main.yaml", manifest, fs.ModePerm)
+		err = u.ManifestsFiles.AddOverride("./main.yaml", string(manifest), fs.ModePerm)
 		if err != nil {
 			return fmt.Errorf("add remote file: %w", err)
 		}
@@ -150,11 +191,25 @@ func (u *Unit) ReadManifestsPath(src string) error {
 
 }
 
-func (u *Unit) ReadConfig(spec map[string]interface{}, stack *project.Stack) error {
+// ReadSpec reads and checks common options of units applied with kubectl.
+func (u *Unit) ReadSpec(spec map[string]interface{}) error {
 	err := utils.YAMLInterfaceToType(spec, u)
 	if err != nil {
 		return err
 	}
+	if u.Wait != nil {
+		if err = u.Wait.check(); err != nil {
+			return fmt.Errorf("read unit '%v': %w", u.Name(), err)
+		}
+	}
+	return nil
+}
+
+func (u *Unit) ReadConfig(spec map[string]interface{}, stack *project.Stack) error {
+	err := u.ReadSpec(spec)
+	if err != nil {
+		return err
+	}
 	err = u.ReadManifestsPath(u.Path)
 	if err != nil {
 		return fmt.Errorf("read unit '%v': read manifests: %w", u.Name(), err)
@@ -173,7 +228,7 @@ func (u *Unit) ReadConfig(spec map[string]interface{}, stack *project.Stack) err
 	}
 	u.UnitKind = u.KindKey()
 	// u.StatePtr.ManifestsFiles = u.ManifestsFiles
-	u.CacheDir = filepath.Join(u.Project().CodeCacheDir, u.Key())
+	u.CacheDir = filepath.Join(u.Project().CodeCacheDir, project.UnitDirName(u.Key()))
 	return err
 }
 
@@ -196,11 +251,6 @@ func (u *Unit) Apply() error {
 	return nil
 }
 
-// Plan unit.
-func (u *Unit) Plan() error {
-	return nil
-}
-
 // Destroy unit.
 func (u *Unit) Destroy() error {
 	err := u.Unit.Destroy()
@@ -317,41 +367,18 @@ func (u *Unit) Build() error {
 		return err
 	}
 	if u.ProjectPtr.OwnState != nil {
-		stateUnit, exists := u.ProjectPtr.OwnState.Units[u.Key()]
-		deleteYAML := ""
-		if exists {
-			stateUnit, ok := stateUnit.(*Unit)
-			if !ok {
-				log.Trace("internal error")
-			}
-			stateManifests, _ := stateUnit.GetManifestsMap()
-			manifests, createNSList := u.GetManifestsMap()
-			u.createNSList = createNSList
-			i := 0
-			for key, man := range stateManifests {
-				// log.Warnf("stateManifests: %v", key)
-				if i > 0 {
-					deleteYAML = deleteYAML + "\n---\n"
-				}
-				if _, ok := manifests[key]; !ok {
-					manifestData, err := yaml.Marshal(man)
-					if err != nil {
-						return err
-					}
-					deleteYAML = deleteYAML + string(manifestData)
-				}
-			}
-		}
-		if deleteYAML != "" {
-			u.manifestsForDelete = &common.FilesListT{}
-			u.manifestsForDelete.AddOverride("./delete/main.yaml", deleteYAML, fs.ModePerm)
-			err := u.manifestsForDelete.WriteFiles(u.CacheDir)
-			if err != nil {
-				return err
-			}
+		if _, exists := u.ProjectPtr.OwnState.Units[u.Key()]; exists {
+			_, u.createNSList = u.GetManifestsMap()
 		}
 	}
+	u.pruneList = u.pruneInventory()
+	for _, item := range u.pruneList {
+		log.Debugf("Unit '%v': object will be pruned: %v", u.Key(), item)
+	}
 
 	u.fillShellUnit()
+	if err = u.writePhases(); err != nil {
+		return fmt.Errorf("build unit '%v': %w", u.Key(), err)
+	}
 	return u.ManifestsFiles.WriteFiles(filepath.Join(u.CacheDir, "workdir"))
 }
@@ -0,0 +1,94 @@
+package base
+
+import (
+	"fmt"
+	"time"
+)
+
+const defaultWaitTimeout = "300s"
+
+// WaitConditionT describes a custom condition checked with 'kubectl wait --for'.
+type WaitConditionT struct {
+	// Resource in kubectl format, e.g. 'deployment/app' or 'pods -l app=web'.
+	Resource  string `yaml:"resource" json:"resource"`
+	For       string `yaml:"for" json:"for"`
+	Namespace string `yaml:"namespace,omitempty" json:"namespace,omitempty"`
+}
+
+// WaitSpecT describes readiness checks running after objects are applied.
+type WaitSpecT struct {
+	Timeout string `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	// Defaults enables per-kind checks of applied objects, true if not set.
+	Defaults   *bool            `yaml:"defaults,omitempty" json:"defaults,omitempty"`
+	Conditions []WaitConditionT `yaml:"conditions,omitempty" json:"conditions,omitempty"`
+}
+
+// rolloutKinds are workloads checked with 'kubectl rollout status'.
+var rolloutKinds = map[string]bool{"Deployment": true, "StatefulSet": true, "DaemonSet": true}
+
+// waitKindConditions are conditions checked with 'kubectl wait' by default.
+var waitKindConditions = map[string]string{
+	"CustomResourceDefinition": "condition=Established",
+	"Job":                      "condition=Complete",
+}
+
+func (w *WaitSpecT) check() error {
+	if w.Timeout != "" {
+		if _, err := time.ParseDuration(w.Timeout); err != nil {
+			return fmt.Errorf("wait: bad timeout '%v': %w", w.Timeout, err)
+		}
+	}
+	for _, cond := range w.Conditions {
+		if cond.Resource == "" || cond.For == "" {
+			return fmt.Errorf("wait: condition requires 'resource' and 'for' fields")
+		}
+	}
+	return nil
+}
+
+// waitTimeout returns the timeout of readiness checks.
+func (u *Unit) waitTimeout() string {
+	if u.Wait == nil || u.Wait.Timeout == "" {
+		return defaultWaitTimeout
+	}
+	return u.Wait.Timeout
+}
+
+// waitCommands returns kubectl commands which block until applied objects are ready. Any failed check fails the apply.
+func (u *Unit) waitCommands() []string {
+	if u.Wait == nil {
+		return nil
+	}
+	timeout := u.waitTimeout()
+	res := []string{}
+	objOpts := func(namespace string) string {
+		opts := u.kubectlOpts()
+		if namespace != "" {
+			opts = fmt.Sprintf("%s -n %s", opts, namespace)
+		}
+		return opts
+	}
+	if u.Wait.Defaults == nil || *u.Wait.Defaults {
+		crdChecks, objChecks := []string{}, []string{}
+		for _, item := range u.inventory() {
+			switch {
+			case rolloutKinds[item.Kind]:
+				objChecks = append(objChecks, fmt.Sprintf("%s rollout status %s --timeout=%s %s", kubectlBin, objOpts(item.Namespace), timeout, item.resource()))
+			case waitKindConditions[item.Kind] != "":
+				check := fmt.Sprintf("%s wait %s --for=%s --timeout=%s %s", kubectlBin, objOpts(item.Namespace), waitKindConditions[item.Kind], timeout, item.resource())
+				if item.Kind == "CustomResourceDefinition" {
+					crdChecks = append(crdChecks, check)
+				} else {
+					objChecks = append(objChecks, check)
+				}
+			}
+		}
+		// CRDs go first, so custom resources in custom conditions are known by the API server.
+		res = append(res, crdChecks...)
+		res = append(res, objChecks...)
+	}
+	for _, cond := range u.Wait.Conditions {
+		res = append(res, fmt.Sprintf("%s wait %s --for=%s --timeout=%s %s", kubectlBin, objOpts(cond.Namespace), cond.For, timeout, cond.Resource))
+	}
+	return res
+}
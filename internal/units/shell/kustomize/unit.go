@@ -0,0 +1,140 @@
+package kustomize
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/apex/log"
+	"github.com/shalb/cluster.dev/internal/config"
+	"github.com/shalb/cluster.dev/internal/project"
+	"github.com/shalb/cluster.dev/internal/units/shell/k8s_manifest"
+	"github.com/shalb/cluster.dev/pkg/executor"
+	"github.com/shalb/cluster.dev/pkg/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// Unit renders manifests with 'kustomize build' and applies them as k8s-manifest unit.
+type Unit struct {
+	base.Unit
+	// Images and Patches are kustomization fields added to the kustomization from the path, in kustomize format.
+	Images  []map[string]interface{} `yaml:"images,omitempty" json:"-"`
+	Patches []map[string]interface{} `yaml:"patches,omitempty" json:"-"`
+}
+
+func (u *Unit) KindKey() string {
+	return unitKind
+}
+
+// kustomizeCommand returns the command to build kustomization: kustomize binary if installed, 'kubectl kustomize'
+// otherwise.
+func kustomizeCommand() string {
+	if _, err := exec.LookPath("kustomize"); err == nil {
+		return "kustomize build"
+	}
+	return "kubectl kustomize"
+}
+
+func (u *Unit) ReadConfig(spec map[string]interface{}, stack *project.Stack) error {
+	err := u.Unit.ReadSpec(spec)
+	if err != nil {
+		return err
+	}
+	err = utils.YAMLInterfaceToType(spec, u)
+	if err != nil {
+		return err
+	}
+	if u.Path == "" {
+		return fmt.Errorf("read unit '%v': option 'path' is required", u.Name())
+	}
+	manifests, err := u.build()
+	if err != nil {
+		return fmt.Errorf("read unit '%v': %w", u.Name(), err)
+	}
+	err = u.ManifestsFiles.AddOverride("./main.yaml", manifests, fs.ModePerm)
+	if err != nil {
+		return fmt.Errorf("read unit '%v': %w", u.Name(), err)
+	}
+	u.UnitKind = u.KindKey()
+	u.CacheDir = filepath.Join(u.Project().CodeCacheDir, project.UnitDirName(u.Key()))
+	return nil
+}
+
+// kustomizationPath returns the absolute path of the local kustomization, remote kustomizations are returned as is.
+func (u *Unit) kustomizationPath() string {
+	if !utils.IsLocalPath(u.Path) || utils.IsAbsolutePath(u.Path) {
+		return u.Path
+	}
+	return filepath.Join(config.Global.WorkingDir, u.StackPtr.TemplateDir, u.Path)
+}
+
+// build renders the kustomization. If images or patches are set, they are added with the overlay in the temporary
+// dir, which refers to the kustomization from the path as the resource. Output markers in images and patches are
+// rendered as strings and replaced later as in manifests.
+func (u *Unit) build() (string, error) {
+	dir := u.kustomizationPath()
+	if len(u.Images) > 0 || len(u.Patches) > 0 {
+		overlayDir, err := os.MkdirTemp("", "cdev-kustomize-")
+		if err != nil {
+			return "", err
+		}
+		defer os.RemoveAll(overlayDir)
+		if err = u.writeOverlay(overlayDir, dir); err != nil {
+			return "", err
+		}
+		dir = overlayDir
+	}
+	rn, err := executor.NewExecutor(config.Global.WorkingDir, &config.Interrupted)
+	if err != nil {
+		return "", err
+	}
+	cmd := fmt.Sprintf("%s %s", kustomizeCommand(), dir)
+	out, errMsg, err := rn.RunMutely(cmd)
+	if err != nil {
+		log.Debugf("Kustomize build output: %v", out)
+		return "", fmt.Errorf("kustomize build: %w, error output:\n %v", err, errMsg)
+	}
+	return out, nil
+}
+
+// writeOverlay writes kustomization.yaml with images and patches. Patch files set by 'path' are inlined, since
+// kustomize doesn't load files outside the kustomization root.
+func (u *Unit) writeOverlay(overlayDir, resource string) error {
+	patches := []map[string]interface{}{}
+	for _, patch := range u.Patches {
+		inlined := map[string]interface{}{}
+		for k, v := range patch {
+			inlined[k] = v
+		}
+		if path, ok := patch["path"].(string); ok {
+			if !utils.IsAbsolutePath(path) {
+				path = filepath.Join(config.Global.WorkingDir, u.StackPtr.TemplateDir, path)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("read patch: %w", err)
+			}
+			delete(inlined, "path")
+			inlined["patch"] = string(data)
+		}
+		patches = append(patches, inlined)
+	}
+	kustomization := map[string]interface{}{
+		"apiVersion": "kustomize.config.k8s.io/v1beta1",
+		"kind":       "Kustomization",
+		"resources":  []string{resource},
+	}
+	if len(u.Images) > 0 {
+		kustomization["images"] = u.Images
+	}
+	if len(patches) > 0 {
+		kustomization["patches"] = patches
+	}
+	data, err := yaml.Marshal(kustomization)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(overlayDir, "kustomization.yaml"), data, 0644)
+}
@@ -0,0 +1,191 @@
+package base
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/Masterminds/semver"
+	"github.com/apex/log"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/shalb/cluster.dev/internal/config"
+	"github.com/shalb/cluster.dev/internal/project"
+	"github.com/shalb/cluster.dev/pkg/executor"
+	"github.com/shalb/cluster.dev/pkg/hcltools"
+	"github.com/shalb/cluster.dev/pkg/utils"
+)
+
+const (
+	engineTerraform = "terraform"
+	engineTofu      = "tofu"
+)
+
+// engineProducts are names of engines in 'version' command output.
+var engineProducts = map[string]string{
+	engineTerraform: "Terraform",
+	engineTofu:      "OpenTofu",
+}
+
+var engineVersionRe = regexp.MustCompile(`^(Terraform|OpenTofu) v(\S+)`)
+
+// checkedEngines contains results of engine binaries checks, each binary is checked once per run.
+var checkedEngines = struct {
+	sync.Mutex
+	res map[EngineSpec]error
+}{res: map[EngineSpec]error{}}
+
+// EngineSpec describes the binary which runs the unit code: Terraform or OpenTofu.
+type EngineSpec struct {
+	Name string `yaml:"name" json:"name"`
+	// Path to the binary, the engine name is used by default.
+	Path string `yaml:"path,omitempty" json:"path,omitempty"`
+	// Version is the constraint of the binary version, e.g. '>= 1.6.0'.
+	Version string `yaml:"version,omitempty" json:"version,omitempty"`
+	// Encryption is the body of OpenTofu state encryption block in HCL.
+	Encryption string `yaml:"encryption,omitempty" json:"encryption,omitempty"`
+}
+
+// readEngineSpec reads the 'engine' option of the unit. If the unit has no option, the option of the stack or
+// the project is used. Returns nil if the engine is not set.
+func readEngineSpec(spec map[string]interface{}, stack *project.Stack) (*EngineSpec, error) {
+	raw, exists := spec["engine"]
+	if !exists && stack != nil {
+		raw, exists = stack.ConfigData["engine"]
+		if !exists {
+			if projectConf, ok := stack.ConfigData["project"].(map[string]interface{}); ok {
+				raw, exists = projectConf["engine"]
+			}
+		}
+	}
+	if !exists {
+		return nil, nil
+	}
+	res := EngineSpec{}
+	switch engine := raw.(type) {
+	case string:
+		res.Name = engine
+	case map[string]interface{}:
+		if err := utils.YAMLInterfaceToType(engine, &res); err != nil {
+			return nil, fmt.Errorf("read 'engine': %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("read 'engine': should be the engine name or map, not %T", raw)
+	}
+	if err := res.check(); err != nil {
+		return nil, fmt.Errorf("read 'engine': %w", err)
+	}
+	return &res, nil
+}
+
+func (e *EngineSpec) check() error {
+	if _, exists := engineProducts[e.Name]; !exists {
+		return fmt.Errorf("unknown engine '%v', allowed: %v, %v", e.Name, engineTerraform, engineTofu)
+	}
+	if e.Version != "" {
+		if _, err := hcltools.ParseVersionConstraint(e.Version); err != nil {
+			return err
+		}
+	}
+	if e.Encryption != "" {
+		if e.Name != engineTofu {
+			return fmt.Errorf("state encryption is supported by OpenTofu only, set engine name '%v'", engineTofu)
+		}
+		if _, diags := hclwrite.ParseConfig([]byte(e.Encryption), "encryption", hcl.InitialPos); diags.HasErrors() {
+			return fmt.Errorf("parse 'encryption': %v", diags.Error())
+		}
+	}
+	return nil
+}
+
+// binary returns the path to the engine binary.
+func (e *EngineSpec) binary() string {
+	if e.Path != "" {
+		return e.Path
+	}
+	return e.Name
+}
+
+// EngineBin returns the binary to run the unit code. Without the engine setting CDEV_TF_BINARY is used, if set.
+func (u *Unit) EngineBin() string {
+	if u.Engine != nil {
+		return u.Engine.binary()
+	}
+	if envTfBin, exists := os.LookupEnv("CDEV_TF_BINARY"); exists {
+		return envTfBin
+	}
+	return terraformBin
+}
+
+// checkEngine checks that the engine binary exists, is the expected product (Terraform or OpenTofu) and matches
+// the version constraint.
+func (u *Unit) checkEngine() error {
+	if u.Engine == nil {
+		return nil
+	}
+	checkedEngines.Lock()
+	defer checkedEngines.Unlock()
+	if err, checked := checkedEngines.res[*u.Engine]; checked {
+		return err
+	}
+	err := u.Engine.checkBinary(u.CacheDir)
+	checkedEngines.res[*u.Engine] = err
+	return err
+}
+
+func (e *EngineSpec) checkBinary(dir string) error {
+	rn, err := executor.NewExecutor(dir, &config.Interrupted)
+	if err != nil {
+		return err
+	}
+	out, errMsg, err := rn.RunMutely(fmt.Sprintf("%s version", e.binary()))
+	if err != nil {
+		return fmt.Errorf("engine '%v': run '%v version': %w, error output:\n %v\nInstall %v or set the path to the binary in 'engine.path'", e.Name, e.binary(), err, errMsg, engineProducts[e.Name])
+	}
+	parsed := engineVersionRe.FindStringSubmatch(strings.TrimSpace(out))
+	if parsed == nil {
+		return fmt.Errorf("engine '%v': unknown output of '%v version': %v", e.Name, e.binary(), out)
+	}
+	if parsed[1] != engineProducts[e.Name] {
+		return fmt.Errorf("engine '%v': '%v' is %v, not %v", e.Name, e.binary(), parsed[1], engineProducts[e.Name])
+	}
+	log.Debugf("Engine '%v': %v version %v", e.Name, e.binary(), parsed[2])
+	if e.Version == "" {
+		return nil
+	}
+	ver, err := semver.NewVersion(parsed[2])
+	if err != nil {
+		return fmt.Errorf("engine '%v': parse version '%v': %w", e.Name, parsed[2], err)
+	}
+	constraint, err := hcltools.ParseVersionConstraint(e.Version)
+	if err != nil {
+		return err
+	}
+	if !constraint.Check(ver) {
+		return fmt.Errorf("engine '%v': %v version %v doesn't match required version '%v'", e.Name, e.binary(), parsed[2], e.Version)
+	}
+	return nil
+}
+
+// appendEncryptionBlock adds OpenTofu state encryption block to the 'terraform' block body.
+func (u *Unit) appendEncryptionBlock(tfBody *hclwrite.Body) error {
+	if u.Engine == nil || u.Engine.Encryption == "" {
+		return nil
+	}
+	f, diags := hclwrite.ParseConfig([]byte(u.Engine.Encryption), "encryption", hcl.InitialPos)
+	if diags.HasErrors() {
+		return fmt.Errorf("parse 'engine.encryption': %v", diags.Error())
+	}
+	tfBody.AppendNewBlock("encryption", nil).Body().AppendUnstructuredTokens(f.BuildTokens(nil))
+	return nil
+}
+
+// formatEngineCode formats the code with the encryption block, which is added as is.
+func (u *Unit) formatEngineCode(code []byte) []byte {
+	if u.Engine == nil || u.Engine.Encryption == "" {
+		return code
+	}
+	return hclwrite.Format(code)
+}
@@ -13,15 +13,18 @@ import (
 // genBackendCodeBlock generate backend code block for this unit.
 func (u *Unit) genBackendCodeBlock() ([]byte, error) {
 
-	f, err := (*u.BackendPtr).GetBackendHCL(u.StackName(), u.Name())
+	f, err := (*u.BackendPtr).GetBackendHCL(u.StackName(), project.ConvertToHCLName(u.Name()))
 	if err != nil {
 		log.Debug(err.Error())
 		return nil, err
 	}
+	tb := f.Body().Blocks()[0]
+	if err = u.appendEncryptionBlock(tb.Body()); err != nil {
+		return nil, err
+	}
 	if len(u.RequiredProviders) < 1 {
-		return f.Bytes(), nil
+		return u.formatEngineCode(f.Bytes()), nil
 	}
-	tb := f.Body().Blocks()[0]
 	tfBlock := tb.Body().AppendNewBlock("required_providers", []string{})
 	for name, prov := range u.RequiredProviders {
 
@@ -31,7 +34,7 @@ func (u *Unit) genBackendCodeBlock() ([]byte, error) {
 		}
 		tfBlock.Body().SetAttributeValue(name, reqProvs)
 	}
-	return f.Bytes(), nil
+	return u.formatEngineCode(f.Bytes()), nil
 }
 
 // genDepsRemoteStates generate terraform remote states for all dependencies of this unit.
@@ -54,7 +57,7 @@ func (u *Unit) genDepsRemoteStates() ([]byte, error) {
 			continue
 		}
 		// log.Warnf("%v", modBackend)
-		rs, err := modBackend.GetRemoteStateHCL(dep.Unit.Stack().Name, dep.Unit.Name())
+		rs, err := modBackend.GetRemoteStateHCL(dep.Unit.Stack().Name, project.ConvertToHCLName(dep.Unit.Name()))
 		if err != nil {
 			log.Debug(err.Error())
 			return nil, err
@@ -90,6 +93,12 @@ func (u *Unit) Build() error {
 	if err != nil {
 		return fmt.Errorf("build unit %v: %w\n%v", u.Key(), err, u.CreateFiles.SPrintLs())
 	}
+	// Project-wide lock file, created by 'cdev providers lock', pins providers versions of all units.
+	if lock := u.ProjectPtr.ProvidersLock(); lock != nil {
+		if err = u.CreateFiles.AddOverride(LockFileName, string(lock), fs.ModePerm); err != nil {
+			return fmt.Errorf("build unit %v: %w", u.Key(), err)
+		}
+	}
 	// Create remote_state.tf
 	remoteStates, err := u.genDepsRemoteStates()
 	if err != nil {
@@ -128,9 +137,8 @@ func (u *Unit) replaceRemoteStatesForBash(cmd *string) error {
 			return fmt.Errorf("internal error, debug: %+v", marker)
 			//marker.TargenStackName = m.Stack().Name
 		}
-		refStr := DependencyToBashRemoteState(marker)
-		c := strings.ReplaceAll(*cmd, hash, refStr)
-		cmd = &c
+		refStr := DependencyToBashRemoteState(marker, u.ProjectPtr.Units[marker.UnitKey()])
+		*cmd = strings.ReplaceAll(*cmd, hash, refStr)
 	}
 	return nil
 }
@@ -31,6 +31,9 @@ func (u *Unit) RemoteStatesScanner(data reflect.Value, unit project.Unit) (refle
 			modKey := fmt.Sprintf("%s.%s", stackName, link.TargetUnitName)
 			depUnit, exists := unit.Project().Units[modKey]
 			if !exists {
+				if err := unit.Project().CheckUnitDisabled(modKey); err != nil {
+					return reflect.ValueOf(nil), fmt.Errorf("unit '%s.%s' refers to remote state of disabled unit: %w", unit.Stack().Name, unit.Name(), err)
+				}
 				return reflect.ValueOf(nil), fmt.Errorf("Depend unit does not exists. Src: '%s.%s', depend: '%s'", unit.Stack().Name, unit.Name(), modKey)
 			}
 			if link.Unit == nil {
@@ -14,6 +14,8 @@ type UnitDiffSpec struct {
 	// BackendName string      `json:"backend_name"`
 	common.UnitDiffSpec
 	Providers interface{} `json:"providers,omitempty"`
+	// Engine change updates the unit, so the state keeps the engine which was used for the last apply.
+	Engine *EngineSpec `json:"engine,omitempty"`
 }
 
 func (u *Unit) GetStateUnit() *Unit {
@@ -52,6 +54,12 @@ func (u *Unit) GetUnitDiff() UnitDiffSpec {
 		UnitDiffSpec: diff,
 		Providers:    u.Providers,
 	}
+	if u.Engine != nil {
+		// Encryption is a part of the generated backend code, which is in the diff already.
+		engine := *u.Engine
+		engine.Encryption = ""
+		st.Engine = &engine
+	}
 	st.UnitDiffSpec.ApplyConf = nil
 	st.UnitDiffSpec.ApplyConf = nil
 	st.UnitDiffSpec.Env = nil
@@ -81,10 +89,13 @@ func (u *Unit) LoadState(spec interface{}, modKey string, p *project.StateProjec
 	if err != nil {
 		return err
 	}
-	u.fillShellUnit()
 	err = utils.JSONCopy(spec, &u)
-
-	return err
+	if err != nil {
+		return err
+	}
+	// Engine is read from the state.
+	u.fillShellUnit()
+	return nil
 }
 
 // ReplaceRemoteStatesForDiff replace remote state markers in struct to <remote state stack.mod.output> to show in diff.
@@ -37,6 +37,7 @@ func (d *TerraformTemplateDriver) AddTemplateFunctions(mp template.FuncMap, p *p
 		}
 		if dep.TargetStackName == "this" {
 			dep.TargetStackName = s.Name
+			dep.TargetUnitName = s.UnitsPrefix + dep.TargetUnitName
 		}
 		return p.UnitLinks.Set(&dep)
 
@@ -2,7 +2,6 @@ package base
 
 import (
 	"fmt"
-	"os"
 	"path/filepath"
 
 	"github.com/apex/log"
@@ -31,6 +30,7 @@ type Unit struct {
 	Providers         interface{}                 `yaml:"-" json:"providers,omitempty"`
 	RequiredProviders map[string]RequiredProvider `yaml:"-" json:"required_providers,omitempty"`
 	InitDone          bool                        `yaml:"-" json:"-"` // True if unit was initted in this session.
+	Engine            *EngineSpec                 `yaml:"-" json:"engine,omitempty"`
 	// StateData         project.Unit                `yaml:"-" json:"-"`
 	// SavedState        string
 }
@@ -46,47 +46,51 @@ func (u *Unit) AddRequiredProvider(name, source, version string) {
 }
 
 func (u *Unit) fillShellUnit() {
-	// Check if CDEV_TF_BINARY is set to change terraform binary name.
-	envTfBin, exists := os.LookupEnv("CDEV_TF_BINARY")
-	if exists {
-		terraformBin = envTfBin
-	}
+	bin := u.EngineBin()
 	u.InitConf = &common.OperationConfig{
 		Commands: []interface{}{
-			fmt.Sprintf("%[1]s init", terraformBin),
+			fmt.Sprintf("%[1]s init", bin),
 		},
 	}
 	u.ApplyConf = &common.OperationConfig{
 		Commands: []interface{}{
-			fmt.Sprintf("%s apply -auto-approve", terraformBin),
+			fmt.Sprintf("%s apply -auto-approve", bin),
 		},
 	}
 	u.DestroyConf = &common.OperationConfig{
 		Commands: []interface{}{
-			fmt.Sprintf("%s destroy -auto-approve", terraformBin),
+			fmt.Sprintf("%s destroy -auto-approve", bin),
 		},
 	}
 	u.PlanConf = &common.OperationConfig{
 		Commands: []interface{}{
-			fmt.Sprintf("%s plan", terraformBin),
+			fmt.Sprintf("%s plan", bin),
 		},
 	}
 	u.GetOutputsConf = &common.OutputsConfigSpec{
-		Command: fmt.Sprintf("%s output -json", terraformBin),
+		Command: fmt.Sprintf("%s output -json", bin),
 		Type:    "terraform",
 	}
 	u.OutputParsers["terraform"] = TerraformJSONParser
 	u.Env["TF_PLUGIN_CACHE_DIR"] = config.Global.PluginsCacheDir
 	u.Env["TF_PLUGIN_CACHE_MAY_BREAK_DEPENDENCY_LOCK_FILE"] = "true"
+	if config.Global.TerraformCLIConfig != "" {
+		u.Env["TF_CLI_CONFIG_FILE"] = config.Global.TerraformCLIConfig
+	}
 }
 
 func (u *Unit) ReadConfig(spec map[string]interface{}, stack *project.Stack) error {
+	engine, err := readEngineSpec(spec, stack)
+	if err != nil {
+		return err
+	}
+	u.Engine = engine
 	u.fillShellUnit()
 	providers, exists := spec["providers"]
 	if exists {
 		u.Providers = providers
 	}
-	u.CacheDir = filepath.Join(u.Project().CodeCacheDir, u.Key())
+	u.CacheDir = filepath.Join(u.Project().CodeCacheDir, project.UnitDirName(u.Key()))
 	u.InitDone = false
 	return nil
 }
@@ -95,7 +99,12 @@ func (u *Unit) ReadConfig(spec map[string]interface{}, stack *project.Stack) err
 func (u *Unit) Init() error {
 	u.ProjectPtr.InitLock.Lock()
 	defer u.ProjectPtr.InitLock.Unlock()
-	err := u.Unit.Init()
+	err := u.checkEngine()
+	if err != nil {
+		u.SetTainted(true, err)
+		return err
+	}
+	err = u.Unit.Init()
 	if err != nil {
 		return err
 	}
@@ -147,7 +156,7 @@ func (u *Unit) Output() (string, error) {
 		"output",
 	}
 	var cmd = ""
-	cmd += fmt.Sprintf("%s output -json", terraformBin)
+	cmd += fmt.Sprintf("%s output -json", u.EngineBin())
 
 	var errMsg []byte
 	res, errMsg, err := rn.Run(cmd)
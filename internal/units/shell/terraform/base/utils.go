@@ -7,10 +7,17 @@ import (
 )
 
 func DependencyToRemoteStateRef(dep *project.ULinkT) (remoteStateRef string) {
-	remoteStateRef = fmt.Sprintf("data.terraform_remote_state.%s-%s.outputs.%s", dep.TargetStackName, dep.TargetUnitName, dep.OutputName)
+	remoteStateRef = fmt.Sprintf("data.terraform_remote_state.%s-%s.outputs.%s", dep.TargetStackName, project.ConvertToHCLName(dep.TargetUnitName), dep.OutputName)
 	return
 }
-func DependencyToBashRemoteState(dep *project.ULinkT) (remoteStateRef string) {
-	remoteStateRef = fmt.Sprintf("\"$(terraform -chdir=../%v.%v/ output -raw %v)\"", dep.TargetStackName, dep.TargetUnitName, dep.OutputName)
+
+// DependencyToBashRemoteState returns the shell command which reads the output from the initialized dir of the target
+// unit with its engine binary (terraform or tofu).
+func DependencyToBashRemoteState(dep *project.ULinkT, target project.Unit) (remoteStateRef string) {
+	tfBin := terraformBin
+	if engineUnit, ok := target.(interface{ EngineBin() string }); ok {
+		tfBin = engineUnit.EngineBin()
+	}
+	remoteStateRef = fmt.Sprintf("\"$(%v -chdir='../%v/' output -raw %v)\"", tfBin, project.UnitDirName(dep.UnitKey()), dep.OutputName)
 	return
 }
@@ -0,0 +1,86 @@
+package base
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/shalb/cluster.dev/internal/config"
+	"github.com/shalb/cluster.dev/pkg/executor"
+	"github.com/shalb/cluster.dev/pkg/hcltools"
+)
+
+// LockFileName is the name of terraform dependency lock file, created by 'terraform init'.
+const LockFileName = ".terraform.lock.hcl"
+
+// LockedProviderVersion returns the version of the provider (e.g. 'hashicorp/helm') from the lock file in the unit
+// cache dir or, if the unit is not initialized, from the project-wide lock file. Returns an empty string if the
+// provider is not locked.
+func (u *Unit) LockedProviderVersion(source string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(u.CacheDir, LockFileName))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+		data = u.ProjectPtr.ProvidersLock()
+		if data == nil {
+			return "", nil
+		}
+	}
+	providers, err := hcltools.ParseLockFile(data)
+	if err != nil {
+		return "", err
+	}
+	for address, prov := range providers {
+		// Registry host differs for terraform and OpenTofu.
+		if strings.HasSuffix(address, "/"+source) {
+			return prov.Version, nil
+		}
+	}
+	return "", nil
+}
+
+// LockProviders initializes the built unit without the backend and returns providers from the lock file, including
+// providers required by modules. With upgrade the newest versions allowed by constraints are selected. Checksums of
+// all platforms are recorded, so the plugin cache is used only for providers with matching checksums.
+func (u *Unit) LockProviders(upgrade bool) (map[string]hcltools.LockedProvider, error) {
+	if err := u.checkEngine(); err != nil {
+		return nil, err
+	}
+	lockFile := filepath.Join(u.CacheDir, LockFileName)
+	cmd := fmt.Sprintf("%s init -backend=false -input=false", u.EngineBin())
+	if upgrade {
+		if err := os.Remove(lockFile); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		cmd += " -upgrade"
+	}
+	env := []string{}
+	for _, e := range u.EnvSlice() {
+		if !strings.HasPrefix(e, "TF_PLUGIN_CACHE_MAY_BREAK_DEPENDENCY_LOCK_FILE=") {
+			env = append(env, e)
+		}
+	}
+	rn, err := executor.NewExecutor(u.CacheDir, &config.Interrupted, env...)
+	if err != nil {
+		return nil, err
+	}
+	rn.LogLabels = []string{
+		u.StackName(),
+		u.Name(),
+		"providers lock",
+	}
+	_, errMsg, err := rn.Run(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("%w, error output:\n %v", err, string(errMsg))
+	}
+	data, err := os.ReadFile(lockFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]hcltools.LockedProvider{}, nil
+		}
+		return nil, err
+	}
+	return hcltools.ParseLockFile(data)
+}
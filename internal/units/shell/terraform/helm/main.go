@@ -6,7 +6,9 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"regexp"
 
+	"github.com/Masterminds/semver"
 	"github.com/apex/log"
 	"github.com/hashicorp/hcl/v2/hclwrite"
 	"github.com/shalb/cluster.dev/internal/config"
@@ -22,6 +24,8 @@ import (
 
 type Unit struct {
 	base.Unit
+	project.LivePlanResult `yaml:"-" json:"-"`
+
 	Source          string                    `yaml:"-,omitempty" json:"source"`
 	HelmOpts        map[string]interface{}    `yaml:"-" json:"helm_opts,omitempty"`
 	Sets            map[string]interface{}    `yaml:"-" json:"sets,omitempty"`
@@ -33,31 +37,78 @@ type Unit struct {
 	CustomFiles     *common.FilesListT        `yaml:"create_files,omitempty" json:"create_files,omitempty"`
 	ProviderConf    *types.ProviderConfigSpec `yaml:"provider_conf" json:"provider_conf"`
 	ProviderVersion string                    `yaml:"-" json:"provider_version,omitempty"`
+	// RenderedManifests contains manifests of the local chart rendered during the last apply, used by the plan.
+	RenderedManifests map[string]interface{} `yaml:"-" json:"rendered_manifests,omitempty"`
+	// lockedProviderVersion is the helm provider version installed by 'terraform init'.
+	lockedProviderVersion string `yaml:"-" json:"-"`
 }
 
 func (u *Unit) KindKey() string {
 	return unitKind
 }
 
+const helmProviderSource = "hashicorp/helm"
+
 // isHelmProviderV3OrLater determines if the Helm provider version is v3.0.0 or later
-// where kubernetes, registry, and experiments are nested objects instead of blocks
+// where kubernetes, registry, and experiments are nested objects instead of blocks.
+// The version installed by 'terraform init' is used if it is known. Otherwise v3 is assumed unless the
+// 'provider_version' constraint rejects every v3+ version, e.g. '~> 2.10'.
 func (u *Unit) isHelmProviderV3OrLater() bool {
+	if u.lockedProviderVersion != "" {
+		if ver, err := semver.NewVersion(u.lockedProviderVersion); err == nil {
+			return ver.Major() >= 3
+		}
+	}
 	if u.ProviderVersion == "" {
-		// Default to v3 behavior for new installations
 		return true
 	}
-	
-	// Parse version string and check if >= 3.0.0
-	// For simplicity, we'll check if version starts with "3." or higher
-	if len(u.ProviderVersion) > 0 {
-		firstChar := u.ProviderVersion[0]
-		if firstChar >= '3' {
+	constraint, err := hcltools.ParseVersionConstraint(u.ProviderVersion)
+	if err != nil {
+		// The constraint is checked in ReadConfig.
+		return true
+	}
+	return allowsV3(constraint, u.ProviderVersion)
+}
+
+var versionRe = regexp.MustCompile(`\d+(\.\d+){0,2}`)
+
+// allowsV3 returns true if the constraint allows 3.0.0 or any v3+ version mentioned in it, e.g. '~> 3.1'.
+// Next patches of mentioned versions are checked too for constraints like '> 3.0.0'.
+func allowsV3(constraint *semver.Constraints, constraintStr string) bool {
+	if constraint.Check(semver.MustParse("3.0.0")) {
+		return true
+	}
+	for _, v := range versionRe.FindAllString(constraintStr, -1) {
+		ver, err := semver.NewVersion(v)
+		if err != nil || ver.Major() < 3 {
+			continue
+		}
+		next := ver.IncPatch()
+		if constraint.Check(ver) || constraint.Check(&next) {
 			return true
 		}
 	}
 	return false
 }
 
+// readLockedProviderVersion reads the installed helm provider version from the lock file of the initialized unit.
+// The locked version is ignored if it doesn't match 'provider_version', 'terraform init' will change it.
+func (u *Unit) readLockedProviderVersion() error {
+	version, err := u.LockedProviderVersion(helmProviderSource)
+	if err != nil {
+		return fmt.Errorf("read helm provider version: %w", err)
+	}
+	if version != "" && u.ProviderVersion != "" {
+		ver, err := semver.NewVersion(version)
+		constraint, cErr := hcltools.ParseVersionConstraint(u.ProviderVersion)
+		if err != nil || cErr != nil || !constraint.Check(ver) {
+			version = ""
+		}
+	}
+	u.lockedProviderVersion = version
+	return nil
+}
+
 func (u *Unit) genMainCodeBlock() ([]byte, error) {
 	// var marker string
 	// if len(m.valuesFileContent) > 0 {
@@ -232,7 +283,19 @@ func (u *Unit) ReadConfig(spec map[string]interface{}, stack *project.Stack) err
 	if !exists {
 		return fmt.Errorf("read helm chart configuration: option 'chart' is required and should be a string")
 	}
-	if utils.IsLocalPath(helmChartOpt) {
+	repository, _ := u.HelmOpts["repository"].(string)
+	version := ""
+	if v, exists := u.HelmOpts["version"]; exists {
+		version = fmt.Sprintf("%v", v)
+	}
+	vendoredChart, vendored, err := u.ProjectPtr.VendoredHelmChart(repository, helmChartOpt, version)
+	if err != nil {
+		return fmt.Errorf("read helm chart configuration: %w", err)
+	}
+	if vendored {
+		u.HelmOpts["chart"] = vendoredChart
+		delete(u.HelmOpts, "repository")
+	} else if utils.IsLocalPath(helmChartOpt) {
 		if !utils.IsAbsolutePath(helmChartOpt) {
 			absoluteChartPath := filepath.Join(config.Global.ProjectConfigsPath, u.StackPtr.TemplateDir, helmChartOpt)
 			u.HelmOpts["chart"] = absoluteChartPath
@@ -317,8 +380,11 @@ func (u *Unit) ReadConfig(spec map[string]interface{}, stack *project.Stack) err
 	}
 	pv, ok := spec["provider_version"].(string)
 	if ok {
+		if _, err := hcltools.ParseVersionConstraint(pv); err != nil {
+			return fmt.Errorf("read unit config: 'provider_version': %w", err)
+		}
 		u.ProviderVersion = pv
-		u.AddRequiredProvider("helm", "hashicorp/helm", pv)
+		u.AddRequiredProvider("helm", helmProviderSource, pv)
 	}
 	return nil
 }
@@ -375,6 +441,10 @@ func (u *Unit) Build() error {
 	if err != nil {
 		return err
 	}
+	// Lock file exists if the unit cache is kept from the previous run.
+	if err = u.readLockedProviderVersion(); err != nil {
+		return err
+	}
 	mainBlock, err := u.genMainCodeBlock()
 	if err != nil {
 		log.Debug(err.Error())
@@ -391,3 +461,38 @@ func (u *Unit) Build() error {
 func (u *Unit) UpdateProjectRuntimeData(p *project.Project) error {
 	return u.Unit.UpdateProjectRuntimeData(p)
 }
+
+// Init unit. After 'terraform init' the installed helm provider version is read from the lock file. If the provider
+// syntax differs from the one used to build the unit, main.tf is generated again.
+func (u *Unit) Init() error {
+	wasV3 := u.isHelmProviderV3OrLater()
+	err := u.Unit.Init()
+	if err != nil {
+		return err
+	}
+	if err = u.readLockedProviderVersion(); err != nil {
+		return err
+	}
+	if u.isHelmProviderV3OrLater() == wasV3 {
+		return nil
+	}
+	log.Debugf("Unit '%v': installed helm provider %v, generate provider syntax for it", u.Key(), u.lockedProviderVersion)
+	mainBlock, err := u.genMainCodeBlock()
+	if err != nil {
+		return err
+	}
+	if err = u.CreateFiles.AddOverride("main.tf", string(mainBlock), fs.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(u.CacheDir, "main.tf"), mainBlock, fs.ModePerm)
+}
+
+// Destroy unit.
+func (u *Unit) Destroy() error {
+	if !u.InitDone {
+		if err := u.Init(); err != nil {
+			return err
+		}
+	}
+	return u.Unit.Destroy()
+}
@@ -0,0 +1,204 @@
+package helm
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/apex/log"
+	"github.com/shalb/cluster.dev/internal/config"
+	"github.com/shalb/cluster.dev/internal/project"
+	"github.com/shalb/cluster.dev/pkg/executor"
+	"github.com/shalb/cluster.dev/pkg/utils"
+	"gopkg.in/yaml.v3"
+)
+
+var helmBin = "helm"
+
+// renderChart renders the local chart with 'helm template' and returns manifests by object keys,
+// e.g. 'Deployment default/web'. The unit should be built, so outputs of other units are resolved.
+func (u *Unit) renderChart() (map[string]interface{}, error) {
+	tmpDir, err := os.MkdirTemp("", "cdev-helm-render-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+	releaseName, _ := u.HelmOpts["name"].(string)
+	if releaseName == "" {
+		releaseName = u.Name()
+	}
+	cmd := fmt.Sprintf("%s template %s %s", helmBin, releaseName, u.localChart())
+	if namespace, ok := u.HelmOpts["namespace"].(string); ok && namespace != "" {
+		cmd = fmt.Sprintf("%s -n %s", cmd, namespace)
+	}
+	for i, values := range u.ValuesFilesList {
+		valuesFile := filepath.Join(tmpDir, fmt.Sprintf("%02d.yaml", i))
+		if err = os.WriteFile(valuesFile, []byte(values), 0600); err != nil {
+			return nil, err
+		}
+		cmd = fmt.Sprintf("%s -f %s", cmd, valuesFile)
+	}
+	cmd += utils.FlagArgs("--set", u.Sets)
+	rn, err := executor.NewExecutor(tmpDir, &config.Interrupted)
+	if err != nil {
+		return nil, err
+	}
+	out, errMsg, err := rn.RunMutely(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("render chart: %w, error output:\n %v", err, errMsg)
+	}
+	return parseRenderedManifests(out)
+}
+
+// parseRenderedManifests splits 'helm template' output to objects.
+func parseRenderedManifests(out string) (map[string]interface{}, error) {
+	res := map[string]interface{}{}
+	for _, doc := range strings.Split("\n"+out, "\n---") {
+		obj := map[string]interface{}{}
+		if err := yaml.Unmarshal([]byte(doc), &obj); err != nil {
+			return nil, fmt.Errorf("parse rendered manifests: %v", utils.ResolveYamlError([]byte(doc), err))
+		}
+		if len(obj) == 0 {
+			continue
+		}
+		kind, _ := obj["kind"].(string)
+		metadata, _ := obj["metadata"].(map[string]interface{})
+		name, _ := metadata["name"].(string)
+		key := fmt.Sprintf("%s %s", kind, name)
+		if namespace, _ := metadata["namespace"].(string); namespace != "" {
+			key = fmt.Sprintf("%s %s/%s", kind, namespace, name)
+		}
+		if kind == "Secret" {
+			redactSecret(obj)
+		}
+		res[key] = obj
+	}
+	// Use the same types as manifests loaded from the state.
+	normalized := map[string]interface{}{}
+	if err := utils.JSONCopy(res, &normalized); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
+
+// redactedValue replaces values of rendered secrets, so they are not saved to the state and not shown in the plan.
+const redactedValue = "<redacted>"
+
+// redactSecret replaces values of secret 'data' and 'stringData' fields. Keys are kept, so added and removed keys
+// are shown in the diff.
+func redactSecret(obj map[string]interface{}) {
+	for _, field := range []string{"data", "stringData"} {
+		data, ok := obj[field].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for key := range data {
+			data[key] = redactedValue
+		}
+	}
+}
+
+// previousRender returns manifests rendered during the last apply of the unit. Returns false if the unit is in the
+// state but was applied without the render (e.g. by older cdev version).
+func (u *Unit) previousRender() (map[string]interface{}, bool) {
+	if u.ProjectPtr == nil || u.ProjectPtr.OwnState == nil {
+		return map[string]interface{}{}, true
+	}
+	stateUnit, exists := u.ProjectPtr.OwnState.Units[u.Key()]
+	if !exists {
+		return map[string]interface{}{}, true
+	}
+	stateHelmUnit, ok := stateUnit.(*Unit)
+	if !ok || stateHelmUnit.RenderedManifests == nil {
+		return nil, false
+	}
+	return stateHelmUnit.RenderedManifests, true
+}
+
+// diffRendered compares manifests rendered from the current configuration with the previous render.
+func diffRendered(prev, cur map[string]interface{}) *project.LivePlan {
+	res := &project.LivePlan{}
+	keys := []string{}
+	for key := range prev {
+		keys = append(keys, key)
+	}
+	for key := range cur {
+		if _, exists := prev[key]; !exists {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	diffs := []string{}
+	for _, key := range keys {
+		prevObj, inPrev := prev[key]
+		curObj, inCur := cur[key]
+		switch {
+		case !inPrev:
+			res.Created = append(res.Created, key)
+		case !inCur:
+			res.Deleted = append(res.Deleted, key)
+		case !reflect.DeepEqual(prevObj, curObj):
+			res.Changed = append(res.Changed, key)
+		default:
+			continue
+		}
+		diffs = append(diffs, fmt.Sprintf("%v:\n%v", key, utils.Diff(prevObj, curObj, !config.Global.NoColor)))
+	}
+	res.Diff = strings.Join(diffs, "\n")
+	return res
+}
+
+// RenderPlan returns true if the unit has the local or vendored chart, which is rendered by Plan.
+func (u *Unit) RenderPlan() bool {
+	return u.localChart() != ""
+}
+
+// Plan renders the local or vendored chart with 'helm template' and compares manifests with the render of the last
+// apply, saved in the state. Units with remote charts are skipped.
+func (u *Unit) Plan() error {
+	u.SetLivePlan(nil)
+	if u.localChart() == "" {
+		log.Debugf("Unit '%v': remote chart, skip rendered manifests diff", u.Key())
+		return nil
+	}
+	rendered, err := u.renderChart()
+	if err != nil {
+		return fmt.Errorf("plan unit '%v': %w", u.Key(), err)
+	}
+	prev, exists := u.previousRender()
+	if !exists {
+		log.Debugf("Unit '%v': no rendered manifests in the state, skip rendered manifests diff", u.Key())
+		return nil
+	}
+	u.SetLivePlan(diffRendered(prev, rendered))
+	return nil
+}
+
+// Apply unit. Manifests of the local chart are rendered after apply and saved to the state for the next plan.
+func (u *Unit) Apply() error {
+	if !u.InitDone {
+		if err := u.Init(); err != nil {
+			return err
+		}
+	}
+	err := u.Unit.Apply()
+	if err != nil {
+		return err
+	}
+	if u.localChart() == "" {
+		return nil
+	}
+	rendered, err := u.renderChart()
+	if err != nil {
+		log.Warnf("Unit '%v': rendered manifests are not saved: %v", u.Key(), err.Error())
+		return nil
+	}
+	u.RenderedManifests = rendered
+	if savedState, ok := u.SavedState.(*Unit); ok {
+		savedState.RenderedManifests = rendered
+	}
+	return nil
+}
@@ -0,0 +1,254 @@
+package helm
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/apex/log"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"github.com/shalb/cluster.dev/pkg/utils"
+)
+
+const valuesSchemaFile = "values.schema.json"
+
+// localChart returns the path to the local chart dir or the vendored chart archive. Returns an empty string for
+// charts from remote repositories.
+func (u *Unit) localChart() string {
+	chart, _ := u.HelmOpts["chart"].(string)
+	if repository, _ := u.HelmOpts["repository"].(string); repository != "" || !utils.IsLocalPath(chart) {
+		return ""
+	}
+	if _, err := os.Stat(chart); err != nil {
+		return ""
+	}
+	return chart
+}
+
+// readValuesSchema reads values.schema.json from the chart dir or the chart archive. Returns nil if the chart has no
+// schema.
+func readValuesSchema(chart string) ([]byte, error) {
+	if utils.IsDir(chart) {
+		data, err := os.ReadFile(filepath.Join(chart, valuesSchemaFile))
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return data, err
+	}
+	f, err := os.Open(chart)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("read chart archive '%v': %w", chart, err)
+	}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read chart archive '%v': %w", chart, err)
+		}
+		// Schema of the chart itself is in the chart root dir, subcharts schemas are skipped.
+		parts := strings.Split(hdr.Name, "/")
+		if len(parts) == 2 && parts[1] == valuesSchemaFile {
+			return io.ReadAll(tr)
+		}
+	}
+}
+
+// mergedValues merges values in order, as helm does with multiple -f options, and applies inputs (sets) on top.
+func (u *Unit) mergedValues() map[string]interface{} {
+	res := map[string]interface{}{}
+	for _, values := range u.ValuesYAML {
+		mergeValues(res, values)
+	}
+	keys := make([]string, 0, len(u.Sets))
+	for key := range u.Sets {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if strings.Contains(key, "[") {
+			log.Debugf("Unit '%v': input '%v' with list index is skipped in the values validation", u.Key(), key)
+			continue
+		}
+		setValue(res, splitSetKey(key), typedSetValue(u.Sets[key]))
+	}
+	return res
+}
+
+func mergeValues(dst, src map[string]interface{}) {
+	for key, val := range src {
+		srcMap, srcIsMap := val.(map[string]interface{})
+		dstMap, dstIsMap := dst[key].(map[string]interface{})
+		if srcIsMap && dstIsMap {
+			mergeValues(dstMap, srcMap)
+			continue
+		}
+		dst[key] = val
+	}
+}
+
+// typedSetValue converts the string set value to bool, null or integer, as helm does with '--set' values.
+func typedSetValue(val interface{}) interface{} {
+	str, ok := val.(string)
+	if !ok {
+		return val
+	}
+	switch str {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "null":
+		return nil
+	}
+	// Numbers with leading zeros are strings.
+	if len(str) > 1 && str[0] == '0' {
+		return str
+	}
+	if i, err := strconv.ParseInt(str, 10, 64); err == nil {
+		return i
+	}
+	return str
+}
+
+// splitSetKey splits the set name by dots, escaped dots ('\.') are kept in key names.
+func splitSetKey(key string) []string {
+	parts := []string{}
+	current := ""
+	for i := 0; i < len(key); i++ {
+		if key[i] == '\\' && i+1 < len(key) && key[i+1] == '.' {
+			current += "."
+			i++
+			continue
+		}
+		if key[i] == '.' {
+			parts = append(parts, current)
+			current = ""
+			continue
+		}
+		current += string(key[i])
+	}
+	return append(parts, current)
+}
+
+func setValue(values map[string]interface{}, path []string, val interface{}) {
+	for _, key := range path[:len(path)-1] {
+		next, ok := values[key].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			values[key] = next
+		}
+		values = next
+	}
+	values[path[len(path)-1]] = val
+}
+
+// Validate checks unit values and inputs against values.schema.json of the chart. Only local and vendored charts
+// are checked. Values with unresolved outputs of other units are skipped.
+func (u *Unit) Validate() error {
+	chart := u.localChart()
+	if chart == "" {
+		log.Debugf("Unit '%v': remote chart, skip values validation", u.Key())
+		return nil
+	}
+	schemaData, err := readValuesSchema(chart)
+	if err != nil {
+		return err
+	}
+	if schemaData == nil {
+		return nil
+	}
+	compiler := jsonschema.NewCompiler()
+	compiler.Draft = jsonschema.Draft7
+	if err = compiler.AddResource(valuesSchemaFile, bytes.NewReader(schemaData)); err != nil {
+		return fmt.Errorf("read chart values schema: %w", err)
+	}
+	schema, err := compiler.Compile(valuesSchemaFile)
+	if err != nil {
+		return fmt.Errorf("read chart values schema: %w", err)
+	}
+	// Convert values to JSON types expected by the validator.
+	valuesJSON, err := json.Marshal(u.mergedValues())
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(valuesJSON))
+	decoder.UseNumber()
+	var values interface{}
+	if err = decoder.Decode(&values); err != nil {
+		return err
+	}
+	err = schema.Validate(values)
+	if err == nil {
+		return nil
+	}
+	validationErr, ok := err.(*jsonschema.ValidationError)
+	if !ok {
+		return err
+	}
+	errs := []string{}
+	for _, leaf := range validationLeafErrors(validationErr) {
+		str, err := utils.JSONEncodeString(valueByPointer(values, leaf.InstanceLocation))
+		if err == nil && u.ProjectPtr.CheckContainsMarkers(str) {
+			continue
+		}
+		location := leaf.InstanceLocation
+		if location == "" {
+			location = "/"
+		}
+		errs = append(errs, fmt.Sprintf("  %v: %v", location, leaf.Message))
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("values don't match the chart values schema:\n%v", strings.Join(errs, "\n"))
+}
+
+func validationLeafErrors(err *jsonschema.ValidationError) []*jsonschema.ValidationError {
+	if len(err.Causes) == 0 {
+		return []*jsonschema.ValidationError{err}
+	}
+	res := []*jsonschema.ValidationError{}
+	for _, cause := range err.Causes {
+		res = append(res, validationLeafErrors(cause)...)
+	}
+	return res
+}
+
+// valueByPointer returns the value by JSON pointer, e.g. '/image/tag'. Returns nil if the value doesn't exist.
+func valueByPointer(data interface{}, pointer string) interface{} {
+	if pointer == "" {
+		return data
+	}
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		switch v := data.(type) {
+		case map[string]interface{}:
+			data = v[token]
+		case []interface{}:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil
+			}
+			data = v[i]
+		default:
+			return nil
+		}
+	}
+	return data
+}
@@ -107,7 +107,7 @@ func (u *Unit) ReadConfig(spec map[string]interface{}, stack *project.Stack) err
 		if utils.IsLocalPath(fileName) {
 			file, err = os.ReadFile(fileName)
 		} else {
-			file, err = utils.GetFileByUrlByte(fileName)
+			file, err = u.ProjectPtr.GetRemoteFile(fileName)
 		}
 		if err != nil {
 			return fmt.Errorf("reading kubernetes unit '%v': read manifest from '%v': %v", u.Key(), source, err.Error())
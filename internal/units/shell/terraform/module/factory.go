@@ -42,7 +42,7 @@ func NewUnit(spec map[string]interface{}, stack *project.Stack) (*Unit, error) {
 			*unit.CustomFiles = append(*unit.CustomFiles, f)
 		}
 	}
-	unit.LocalModuleCachePath = filepath.Join(stack.ProjectPtr.CodeCacheDir, "../", "terraform", unit.Key())
+	unit.LocalModuleCachePath = filepath.Join(stack.ProjectPtr.CodeCacheDir, "../", "terraform", project.UnitDirName(unit.Key()))
 	return &unit, nil
 }
 
@@ -65,7 +65,7 @@ func (f *Factory) NewFromState(spec map[string]interface{}, unitKey string, p *p
 			*unit.CustomFiles = append(*unit.CustomFiles, f)
 		}
 	}
-	unit.LocalModuleCachePath = filepath.Join(p.CodeCacheDir, "../", "terraform", unit.Key())
+	unit.LocalModuleCachePath = filepath.Join(p.CodeCacheDir, "../", "terraform", project.UnitDirName(unit.Key()))
 	return &unit, nil
 }
 
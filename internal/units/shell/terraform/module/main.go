@@ -39,9 +39,10 @@ func (u *Unit) genMainCodeBlock() ([]byte, error) {
 	f := hclwrite.NewEmptyFile()
 	rootBody := f.Body()
 
-	unitBlock := rootBody.AppendNewBlock("module", []string{u.Name()})
+	unitBlock := rootBody.AppendNewBlock("module", []string{project.ConvertToHCLName(u.Name())})
 	unitBody := unitBlock.Body()
-	if u.Version != "" {
+	// Local paths, including vendored modules, can't have the version.
+	if u.Version != "" && u.LocalModule == nil {
 		unitBody.SetAttributeValue("version", cty.StringVal(u.Version))
 	}
 
@@ -91,7 +92,7 @@ func (u *Unit) genOutputs() ([]byte, error) {
 		}
 		dataBlock := rootBody.AppendNewBlock("output", []string{outputName})
 		dataBody := dataBlock.Body()
-		outputStr := fmt.Sprintf("module.%s.%s", u.Name(), outputName)
+		outputStr := fmt.Sprintf("module.%s.%s", project.ConvertToHCLName(u.Name()), outputName)
 		dataBody.SetAttributeRaw("value", hcltools.CreateTokensForOutput(outputStr))
 		dataBody.SetAttributeValue("sensitive", cty.BoolVal(true))
 		uniqMap[outputName] = true
@@ -122,6 +123,44 @@ func (u *Unit) ReadConfig(spec map[string]interface{}, stack *project.Stack) err
 	if version, ok := spec["version"]; ok {
 		u.Version = fmt.Sprintf("%v", version)
 	}
+	if u.LocalModule == nil {
+		localDir := ""
+		if _, isGit := utils.ParseTerraformGitSource(source); isGit {
+			vendoredDir, vendored, err := u.ProjectPtr.VendoredSource(source)
+			if err != nil {
+				return fmt.Errorf("%v: %w", u.Key(), err)
+			}
+			localDir = vendoredDir
+			if !vendored {
+				// Git sources are pinned to commits from cdev.lock.
+				lockedSource, cachedDir, err := u.ProjectPtr.LockTerraformSource(source)
+				if err != nil {
+					return fmt.Errorf("%v: %w", u.Key(), err)
+				}
+				source = lockedSource
+				if config.Global.Offline {
+					// Terraform can't download modules offline, the module from the templates cache is used as local.
+					localDir = cachedDir
+				}
+			}
+		} else {
+			vendoredDir, vendored, err := u.ProjectPtr.VendoredTerraformModule(source, u.Version, u.EngineBin())
+			if err != nil {
+				return fmt.Errorf("%v: %w", u.Key(), err)
+			}
+			if !vendored && config.Global.Offline {
+				return fmt.Errorf("%v: module source '%v' can't be downloaded in offline mode, run 'cdev vendor' to vendor it", u.Key(), source)
+			}
+			localDir = vendoredDir
+		}
+		if localDir != "" {
+			u.LocalModule = &common.FilesListT{}
+			err := u.LocalModule.ReadDir(localDir, localDir)
+			if err != nil {
+				return fmt.Errorf("%v, reading module: %v", u.Key(), err.Error())
+			}
+		}
+	}
 	u.Source = source
 	mInputs, ok := spec["inputs"].(map[string]interface{})
 	if !ok {
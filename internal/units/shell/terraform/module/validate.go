@@ -0,0 +1,96 @@
+package tfmodule
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/apex/log"
+	"github.com/shalb/cluster.dev/internal/project"
+	"github.com/shalb/cluster.dev/internal/units/shell/terraform/base"
+	"github.com/shalb/cluster.dev/pkg/hcltools"
+	"github.com/shalb/cluster.dev/pkg/utils"
+)
+
+// moduleFiles returns terraform files of the module root dir. For remote modules only already cached (after 'terraform init') modules are used.
+// Returns nil if module files are unavailable.
+func (u *Unit) moduleFiles() (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	if u.LocalModule != nil {
+		for _, f := range *u.LocalModule {
+			files[f.FileName] = []byte(f.Content)
+		}
+		return files, nil
+	}
+	modulesJSON, err := os.ReadFile(filepath.Join(u.CacheDir, ".terraform", "modules", "modules.json"))
+	if err != nil {
+		log.Debugf("Unit '%v': remote module is not cached, skip module validation", u.Key())
+		return nil, nil
+	}
+	modulesList := struct {
+		Modules []struct {
+			Key string `json:"Key"`
+			Dir string `json:"Dir"`
+		} `json:"Modules"`
+	}{}
+	err = utils.JSONDecode(modulesJSON, &modulesList)
+	if err != nil {
+		return nil, fmt.Errorf("read cached modules list: %w", err)
+	}
+	for _, m := range modulesList.Modules {
+		if m.Key != project.ConvertToHCLName(u.Name()) {
+			continue
+		}
+		moduleDir := m.Dir
+		if !filepath.IsAbs(moduleDir) {
+			moduleDir = filepath.Join(u.CacheDir, moduleDir)
+		}
+		tfFiles, err := filepath.Glob(filepath.Join(moduleDir, "*.tf"))
+		if err != nil {
+			return nil, err
+		}
+		for _, fn := range tfFiles {
+			files[filepath.Base(fn)], err = os.ReadFile(fn)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return files, nil
+	}
+	return nil, nil
+}
+
+// Validate checks unit inputs against module variables and outputs required by other units against module outputs.
+func (u *Unit) Validate() error {
+	files, err := u.moduleFiles()
+	if err != nil {
+		return err
+	}
+	if files == nil {
+		return nil
+	}
+	spec, err := hcltools.ParseModuleSpec(files)
+	if err != nil {
+		return err
+	}
+	containsMarkers := func(val interface{}) bool {
+		str, err := utils.JSONEncodeString(val)
+		return err != nil || u.ProjectPtr.CheckContainsMarkers(str)
+	}
+	errs := spec.CheckInputs(u.Inputs, containsMarkers)
+	checked := map[string]bool{}
+	for _, link := range u.ProjectPtr.UnitLinks.ByTargetUnit(u).ByLinkTypes(base.RemoteStateLinkType, project.OutputLinkType).Slice() {
+		if checked[link.OutputName] {
+			continue
+		}
+		checked[link.OutputName] = true
+		if !spec.HasOutput(link.OutputName) {
+			errs = append(errs, fmt.Sprintf("output '%v' is referenced by another unit, but module does not declare it", link.OutputName))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("module '%v':\n    %v", u.Source, strings.Join(errs, "\n    "))
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package hcltools
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// LockedProvider describes 'provider' block of terraform dependency lock file (.terraform.lock.hcl).
+type LockedProvider struct {
+	// Address is the full provider address, e.g. 'registry.terraform.io/hashicorp/helm'.
+	Address     string
+	Version     string
+	Constraints string
+	Hashes      []string
+}
+
+// ParseLockFile parses terraform dependency lock file and returns locked providers by address.
+func ParseLockFile(data []byte) (map[string]LockedProvider, error) {
+	f, diags := hclparse.NewParser().ParseHCL(data, ".terraform.lock.hcl")
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("parse lock file: %v", diags.Error())
+	}
+	body, ok := f.Body.(*hclsyntax.Body)
+	if !ok {
+		return nil, fmt.Errorf("parse lock file: unexpected body type")
+	}
+	res := map[string]LockedProvider{}
+	for _, block := range body.Blocks {
+		if block.Type != "provider" || len(block.Labels) != 1 {
+			continue
+		}
+		prov := LockedProvider{Address: block.Labels[0]}
+		for name, attr := range block.Body.Attributes {
+			val, diags := attr.Expr.Value(nil)
+			if diags.HasErrors() {
+				return nil, fmt.Errorf("parse lock file: provider '%v': %v", prov.Address, diags.Error())
+			}
+			switch {
+			case name == "version" && val.Type() == cty.String:
+				prov.Version = val.AsString()
+			case name == "constraints" && val.Type() == cty.String:
+				prov.Constraints = val.AsString()
+			case name == "hashes" && val.CanIterateElements():
+				for it := val.ElementIterator(); it.Next(); {
+					_, hash := it.Element()
+					if hash.Type() == cty.String {
+						prov.Hashes = append(prov.Hashes, hash.AsString())
+					}
+				}
+			}
+		}
+		res[prov.Address] = prov
+	}
+	return res, nil
+}
+
+// FormatLockFile generates terraform dependency lock file with providers sorted by address, in the same format as
+// terraform writes it.
+func FormatLockFile(header string, providers map[string]LockedProvider) []byte {
+	addresses := make([]string, 0, len(providers))
+	for address := range providers {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+	buf := bytes.NewBufferString(header)
+	for _, address := range addresses {
+		prov := providers[address]
+		fmt.Fprintf(buf, "\nprovider %q {\n", address)
+		fmt.Fprintf(buf, "version = %q\n", prov.Version)
+		if prov.Constraints != "" {
+			fmt.Fprintf(buf, "constraints = %q\n", prov.Constraints)
+		}
+		if len(prov.Hashes) > 0 {
+			buf.WriteString("hashes = [\n")
+			for _, hash := range prov.Hashes {
+				fmt.Fprintf(buf, "%q,\n", hash)
+			}
+			buf.WriteString("]\n")
+		}
+		buf.WriteString("}\n")
+	}
+	return hclwrite.Format(buf.Bytes())
+}
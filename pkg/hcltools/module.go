@@ -0,0 +1,115 @@
+package hcltools
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2/ext/typeexpr"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+var outputNameRe = regexp.MustCompile(`^[A-Za-z][a-zA-Z0-9_\-]*`)
+
+// ModuleVariable describes terraform module 'variable' block.
+type ModuleVariable struct {
+	Name     string
+	Type     cty.Type
+	Required bool
+}
+
+// ModuleSpec describes the interface of terraform module: variables and outputs.
+type ModuleSpec struct {
+	Variables map[string]ModuleVariable
+	Outputs   map[string]bool
+}
+
+// ParseModuleSpec parses terraform files of the module root dir (file name => content) and returns module interface.
+// Files in subdirectories and non-terraform files are ignored.
+func ParseModuleSpec(files map[string][]byte) (*ModuleSpec, error) {
+	res := &ModuleSpec{
+		Variables: make(map[string]ModuleVariable),
+		Outputs:   make(map[string]bool),
+	}
+	parser := hclparse.NewParser()
+	for fileName, content := range files {
+		if filepath.Dir(fileName) != "." || filepath.Ext(fileName) != ".tf" {
+			continue
+		}
+		f, diags := parser.ParseHCL(content, fileName)
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("parse module file: %v", diags.Error())
+		}
+		body, ok := f.Body.(*hclsyntax.Body)
+		if !ok {
+			return nil, fmt.Errorf("parse module file '%v': unexpected body type", fileName)
+		}
+		for _, block := range body.Blocks {
+			if len(block.Labels) != 1 {
+				continue
+			}
+			switch block.Type {
+			case "variable":
+				v := ModuleVariable{
+					Name:     block.Labels[0],
+					Type:     cty.DynamicPseudoType,
+					Required: true,
+				}
+				if _, exists := block.Body.Attributes["default"]; exists {
+					v.Required = false
+				}
+				if typeAttr, exists := block.Body.Attributes["type"]; exists {
+					tp, diags := typeexpr.TypeConstraint(typeAttr.Expr)
+					if !diags.HasErrors() {
+						v.Type = tp
+					}
+				}
+				res.Variables[v.Name] = v
+			case "output":
+				res.Outputs[block.Labels[0]] = true
+			}
+		}
+	}
+	return res, nil
+}
+
+// CheckInputs compares inputs with module variables. skip is called for each input value,
+// if it returns true the type of the value is not checked (for example the value contains unresolved markers).
+func (m *ModuleSpec) CheckInputs(inputs map[string]interface{}, skip func(interface{}) bool) (errs []string) {
+	for name, val := range inputs {
+		variable, exists := m.Variables[name]
+		if !exists {
+			errs = append(errs, fmt.Sprintf("unknown input '%v', module has no such variable", name))
+			continue
+		}
+		if val == nil || skip(val) || variable.Type == cty.DynamicPseudoType {
+			continue
+		}
+		ctyVal, err := InterfaceToCty(val)
+		if err != nil {
+			continue
+		}
+		if _, err := convert.Convert(ctyVal, variable.Type); err != nil {
+			errs = append(errs, fmt.Sprintf("input '%v': type mismatch, module expects '%v': %v", name, typeexpr.TypeString(variable.Type), err.Error()))
+		}
+	}
+	for name, variable := range m.Variables {
+		if !variable.Required {
+			continue
+		}
+		if _, exists := inputs[name]; !exists {
+			errs = append(errs, fmt.Sprintf("required variable '%v' is not set in inputs", name))
+		}
+	}
+	sort.Strings(errs)
+	return
+}
+
+// HasOutput checks if module declares output. Nested paths like 'output.attr' or 'output[0]' are checked by the output name.
+func (m *ModuleSpec) HasOutput(name string) bool {
+	return m.Outputs[outputNameRe.FindString(name)]
+}
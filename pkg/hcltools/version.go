@@ -0,0 +1,46 @@
+package hcltools
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Masterminds/semver"
+)
+
+var partialVersionRe = regexp.MustCompile(`^(>=|<=|!=|=|>|<)?\s*(\d+(\.\d+)?)$`)
+
+// ParseVersionConstraint parses terraform version constraint, e.g. '~> 2.12' or '>= 3.0.0, < 4.0.0'. The pessimistic
+// operator '~>' is converted to the range, as terraform does: '~> 2.12' allows 2.13 and 2.99, but not 3.0.
+// Missing parts of other versions are zeros, as in terraform: '< 3' is '< 3.0.0', not '< 3.x'.
+func ParseVersionConstraint(constraint string) (*semver.Constraints, error) {
+	parts := []string{}
+	for _, part := range strings.Split(constraint, ",") {
+		part = strings.TrimSpace(part)
+		if !strings.HasPrefix(part, "~>") {
+			if m := partialVersionRe.FindStringSubmatch(part); m != nil {
+				part = m[1] + " " + m[2] + strings.Repeat(".0", 2-strings.Count(m[2], "."))
+			}
+			parts = append(parts, part)
+			continue
+		}
+		verStr := strings.TrimSpace(strings.TrimPrefix(part, "~>"))
+		ver, err := semver.NewVersion(verStr)
+		if err != nil {
+			return nil, fmt.Errorf("parse version constraint '%v': %w", constraint, err)
+		}
+		switch strings.Count(verStr, ".") {
+		case 0:
+			parts = append(parts, ">= "+verStr)
+		case 1:
+			parts = append(parts, fmt.Sprintf(">= %s, < %d.0.0", verStr, ver.Major()+1))
+		default:
+			parts = append(parts, fmt.Sprintf(">= %s, < %d.%d.0", verStr, ver.Major(), ver.Minor()+1))
+		}
+	}
+	res, err := semver.NewConstraint(strings.Join(parts, ", "))
+	if err != nil {
+		return nil, fmt.Errorf("parse version constraint '%v': %w", constraint, err)
+	}
+	return res, nil
+}
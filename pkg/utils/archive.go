@@ -0,0 +1,232 @@
+package utils
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ArchiveSource describes template source from the archive url, e.g.
+// https://example.com/template.tar.gz//subdir?checksum=sha256:<hex>.
+type ArchiveSource struct {
+	URL      string
+	SubDir   string
+	Checksum string
+}
+
+// ParseArchiveSource parses the http(s) url of tar.gz, tgz or zip archive. Returns false for other sources.
+func ParseArchiveSource(src string) (ArchiveSource, bool) {
+	res := ArchiveSource{}
+	if !strings.HasPrefix(src, "https://") && !strings.HasPrefix(src, "http://") {
+		return res, false
+	}
+	scheme, rest, _ := strings.Cut(src, "://")
+	rest, query, _ := strings.Cut(rest, "?")
+	rest, res.SubDir, _ = strings.Cut(rest, "//")
+	if !isArchiveName(rest) {
+		return res, false
+	}
+	res.URL = scheme + "://" + rest
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return res, false
+	}
+	res.Checksum = values.Get("checksum")
+	values.Del("checksum")
+	if len(values) > 0 {
+		res.URL += "?" + values.Encode()
+	}
+	return res, true
+}
+
+func isArchiveName(name string) bool {
+	for _, ext := range []string{".tar.gz", ".tgz", ".zip"} {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// Sha256Digest returns the digest of data in the format 'sha256:<hex>'.
+func Sha256Digest(data []byte) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(data))
+}
+
+// VerifyChecksum checks the data by the checksum in the format 'sha256:<hex>'.
+func VerifyChecksum(data []byte, checksum string) error {
+	algo, _, found := strings.Cut(checksum, ":")
+	if !found || algo != "sha256" {
+		return fmt.Errorf("unsupported checksum '%v', expected format 'sha256:<hex>'", checksum)
+	}
+	if digest := Sha256Digest(data); !strings.EqualFold(digest, checksum) {
+		return fmt.Errorf("checksum mismatch: expected %v, actual %v", checksum, digest)
+	}
+	return nil
+}
+
+// ExtractArchive extracts tar.gz or zip archive to the dir. The format is detected by the content.
+func ExtractArchive(data []byte, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		return extractZip(data, dir)
+	}
+	return extractTarGz(bytes.NewReader(data), dir)
+}
+
+// archiveEntryPath returns the path of archive entry in the dir, entries outside the dir are rejected.
+func archiveEntryPath(dir, name string) (string, error) {
+	path := filepath.Join(dir, filepath.FromSlash(name))
+	if path != filepath.Clean(dir) && !strings.HasPrefix(path, filepath.Clean(dir)+string(os.PathSeparator)) {
+		return "", fmt.Errorf("extract archive: illegal file path '%v'", name)
+	}
+	return path, nil
+}
+
+func extractTarGz(r io.Reader, dir string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("extract archive: %w", err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("extract archive: %w", err)
+		}
+		path, err := archiveEntryPath(dir, header.Name)
+		if err != nil {
+			return err
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(path, 0755)
+		case tar.TypeReg:
+			err = writeArchiveFile(path, tr, os.FileMode(header.Mode).Perm())
+		default:
+			// Links and special files are not supported in templates.
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("extract archive: %w", err)
+		}
+	}
+}
+
+func extractZip(data []byte, dir string) error {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return fmt.Errorf("extract archive: %w", err)
+	}
+	for _, f := range zr.File {
+		path, err := archiveEntryPath(dir, f.Name)
+		if err != nil {
+			return err
+		}
+		if f.FileInfo().IsDir() {
+			if err = os.MkdirAll(path, 0755); err != nil {
+				return fmt.Errorf("extract archive: %w", err)
+			}
+			continue
+		}
+		if !f.Mode().IsRegular() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("extract archive: %w", err)
+		}
+		err = writeArchiveFile(path, rc, f.Mode().Perm())
+		rc.Close()
+		if err != nil {
+			return fmt.Errorf("extract archive: %w", err)
+		}
+	}
+	return nil
+}
+
+func writeArchiveFile(path string, r io.Reader, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if perm == 0 {
+		perm = 0644
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(f, r)
+	return err
+}
+
+// TarGzDir packs the dir content to tar.gz archive. Files are sorted and timestamps are omitted, so the same
+// content always gives the same archive. '.git' dirs are skipped.
+func TarGzDir(dir string) ([]byte, error) {
+	files := []string{}
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if d.Type().IsRegular() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, fn := range files {
+		rel, _ := filepath.Rel(dir, fn)
+		info, err := os.Stat(fn)
+		if err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(fn)
+		if err != nil {
+			return nil, err
+		}
+		header := &tar.Header{
+			Name:     filepath.ToSlash(rel),
+			Mode:     int64(info.Mode().Perm()),
+			Size:     int64(len(data)),
+			Typeflag: tar.TypeReg,
+		}
+		if err = tw.WriteHeader(header); err != nil {
+			return nil, err
+		}
+		if _, err = tw.Write(data); err != nil {
+			return nil, err
+		}
+	}
+	if err = tw.Close(); err != nil {
+		return nil, err
+	}
+	if err = gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
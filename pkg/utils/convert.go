@@ -80,6 +80,25 @@ func MergeMaps(mOne, mTwo map[string]interface{}) (res map[string]interface{}) {
 	return
 }
 
+// DeepMergeMaps returns the copy of base map with values from overlay. Nested maps are merged recursively,
+// other values (including lists) are replaced.
+func DeepMergeMaps(base, overlay map[string]interface{}) map[string]interface{} {
+	res := make(map[string]interface{}, len(base))
+	for k, v := range base {
+		res[k] = v
+	}
+	for k, v := range overlay {
+		baseMap, baseIsMap := res[k].(map[string]interface{})
+		overlayMap, overlayIsMap := v.(map[string]interface{})
+		if baseIsMap && overlayIsMap {
+			res[k] = DeepMergeMaps(baseMap, overlayMap)
+		} else {
+			res[k] = v
+		}
+	}
+	return res
+}
+
 // TerraformJSONOutputParse parse data from terraform output --json command to map and line-to-line string
 func TerraformJSONOutputParse(in string) (out map[string]string, stringOut string, err error) {
 	type tfOutputDataSpec struct {
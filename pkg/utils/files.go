@@ -1,11 +1,13 @@
 package utils
 
 import (
+	"crypto/sha256"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 	"syscall"
 
@@ -269,3 +271,36 @@ func ListFilesByRegex(dirPath, pattern string) ([]string, error) {
 
 	return matches, nil
 }
+
+// DirHash returns sha256 hash of the dir content: relative paths and contents of all files, sorted by path.
+// '.git' dirs are skipped.
+func DirHash(dir string) (string, error) {
+	files := []string{}
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if d.Type().IsRegular() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(files)
+	h := sha256.New()
+	for _, fn := range files {
+		rel, _ := filepath.Rel(dir, fn)
+		data, err := os.ReadFile(fn)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s\n%d\n", filepath.ToSlash(rel), len(data))
+		h.Write(data)
+	}
+	return fmt.Sprintf("sha256:%x", h.Sum(nil)), nil
+}
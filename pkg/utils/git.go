@@ -2,6 +2,7 @@ package utils
 
 import (
 	"fmt"
+	"net/url"
 	"path/filepath"
 	"strings"
 
@@ -80,3 +81,112 @@ func ParseGitUrl(gitURL string) (repo GitRepo, err error) {
 	// log.Warnf("ParseGitUrl %+v", repo)
 	return
 }
+
+// GetTemplateAtCommit clones the git repo to the targetDir/templateName dir and checks out the commit. If commit is
+// empty, the latest commit of the ref from the url is checked out. Returns the template dir (with url subdir)
+// and the commit SHA.
+func GetTemplateAtCommit(gitURL, targetDir, templateName, commit string) (string, string, error) {
+	log.Debugf("Getting template from repo: %v, %v, %v, commit '%v'", gitURL, targetDir, templateName, commit)
+	parsedGitURL, err := ParseGitUrl(gitURL)
+	if err != nil {
+		return "", "", fmt.Errorf("get template: %v", err.Error())
+	}
+	var interruptMoc = false
+	repoPath := filepath.Join(targetDir, templateName)
+	if !IsDir(repoPath) {
+		shell, err := executor.NewExecutor(targetDir, &interruptMoc)
+		if err != nil {
+			return "", "", fmt.Errorf("get template: %v", err.Error())
+		}
+		command := "git clone --single-branch --depth=1 "
+		if parsedGitURL.Version != "" {
+			command = command + "-b " + parsedGitURL.Version + " "
+		}
+		command = command + parsedGitURL.URL + " " + templateName
+		_, errOutput, err := shell.RunMutely(command)
+		if err != nil {
+			return "", "", fmt.Errorf("get template: %v\n %v", err.Error(), errOutput)
+		}
+	}
+	shell, err := executor.NewExecutor(repoPath, &interruptMoc)
+	if err != nil {
+		return "", "", fmt.Errorf("get template: %v", err.Error())
+	}
+	var command string
+	if commit == "" {
+		ref := parsedGitURL.Version
+		if ref == "" {
+			ref = "HEAD"
+		}
+		command = fmt.Sprintf("git fetch -q --depth=1 origin %s && git checkout -q FETCH_HEAD", ref)
+	} else {
+		// Fetch the commit only if it is missing in the cached repo.
+		command = fmt.Sprintf("git cat-file -e %[1]s^{commit} 2>/dev/null || git fetch -q --depth=1 origin %[1]s; git checkout -q %[1]s", commit)
+	}
+	_, errOutput, err := shell.RunMutely(command)
+	if err != nil {
+		return "", "", fmt.Errorf("get template: %v\n%v", err.Error(), errOutput)
+	}
+	headCommit, errOutput, err := shell.RunMutely("git rev-parse HEAD")
+	if err != nil {
+		return "", "", fmt.Errorf("get template: %v\n%v", err.Error(), errOutput)
+	}
+	return filepath.Join(repoPath, parsedGitURL.SubDir), strings.TrimSpace(headCommit), nil
+}
+
+// ParseTerraformGitSource converts terraform module git source ('git::https://...', 'github.com/org/repo')
+// to the url supported by ParseGitUrl. Returns false for sources which are not git repos (registry, local, etc.).
+func ParseTerraformGitSource(source string) (string, bool) {
+	src := source
+	switch {
+	case strings.HasPrefix(src, "git::"):
+		src = strings.TrimPrefix(src, "git::")
+	case strings.HasPrefix(src, "github.com/"), strings.HasPrefix(src, "gitlab.com/"), strings.HasPrefix(src, "bitbucket.org/"):
+		src = "https://" + src
+	case strings.HasPrefix(src, "git@"):
+	default:
+		return "", false
+	}
+	if _, err := ParseGitUrl(src); err != nil {
+		return "", false
+	}
+	return src, true
+}
+
+// SetGitSourceRef returns the git source with the ref replaced by the commit. Other query parameters are kept.
+func SetGitSourceRef(source, commit string) string {
+	base, rawQuery, _ := strings.Cut(source, "?")
+	// Malformed parameters are dropped, others are returned with the error.
+	query, _ := url.ParseQuery(rawQuery)
+	query.Set("ref", commit)
+	return base + "?" + query.Encode()
+}
+
+// GetCachedTemplate returns the template from the targetDir/templateName dir cloned before, without access to the git
+// remote. If commit is set, it should be present in the cached repo. Returns the template dir and the commit SHA.
+func GetCachedTemplate(gitURL, targetDir, templateName, commit string) (string, string, error) {
+	parsedGitURL, err := ParseGitUrl(gitURL)
+	if err != nil {
+		return "", "", fmt.Errorf("get template: %v", err.Error())
+	}
+	repoPath := filepath.Join(targetDir, templateName)
+	if !IsDir(repoPath) {
+		return "", "", fmt.Errorf("get template: offline mode: '%v' is not found in the cache, run cdev without offline mode to download it", gitURL)
+	}
+	var interruptMoc = false
+	shell, err := executor.NewExecutor(repoPath, &interruptMoc)
+	if err != nil {
+		return "", "", fmt.Errorf("get template: %v", err.Error())
+	}
+	if commit != "" {
+		_, errOutput, err := shell.RunMutely(fmt.Sprintf("git checkout -q %s", commit))
+		if err != nil {
+			return "", "", fmt.Errorf("get template: offline mode: commit %v of '%v' is not found in the cache: %v\n%v", commit, gitURL, err.Error(), errOutput)
+		}
+	}
+	headCommit, errOutput, err := shell.RunMutely("git rev-parse HEAD")
+	if err != nil {
+		return "", "", fmt.Errorf("get template: %v\n%v", err.Error(), errOutput)
+	}
+	return filepath.Join(repoPath, parsedGitURL.SubDir), strings.TrimSpace(headCommit), nil
+}
@@ -0,0 +1,22 @@
+package utils
+
+import "testing"
+
+func TestSetGitSourceRef(t *testing.T) {
+	const commit = "0123456789abcdef0123456789abcdef01234567"
+	cases := []struct {
+		source string
+		want   string
+	}{
+		{"github.com/org/repo", "github.com/org/repo?ref=" + commit},
+		{"git::https://example.com/repo.git//modules/vpc?ref=v1.2.0", "git::https://example.com/repo.git//modules/vpc?ref=" + commit},
+		{"git::https://example.com/repo.git?depth=1", "git::https://example.com/repo.git?depth=1&ref=" + commit},
+		{"git::https://example.com/repo.git?ref=v1&depth=1", "git::https://example.com/repo.git?depth=1&ref=" + commit},
+		{"git@github.com:org/repo.git?ref=main", "git@github.com:org/repo.git?ref=" + commit},
+	}
+	for _, c := range cases {
+		if got := SetGitSourceRef(c.source, commit); got != c.want {
+			t.Errorf("SetGitSourceRef(%q) = %q, want %q", c.source, got, c.want)
+		}
+	}
+}
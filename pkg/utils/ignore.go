@@ -0,0 +1,126 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+type ignoreRule struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// IgnoreMatcher matches paths with gitignore-style patterns: '*', '?', '[...]', '**', negation with '!',
+// dir-only patterns with trailing '/' and patterns anchored to the base dir with leading or middle '/'.
+type IgnoreMatcher struct {
+	rules []ignoreRule
+}
+
+// NewIgnoreMatcher parses ignore file data. Empty lines and lines started with '#' are skipped.
+func NewIgnoreMatcher(data []byte) *IgnoreMatcher {
+	m := &IgnoreMatcher{}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, " \r\t")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule := ignoreRule{}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		anchored := strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+		expr := ignorePatternToRegexp(line)
+		if !anchored {
+			expr = "(.*/)?" + expr
+		}
+		re, err := regexp.Compile("^" + expr + "$")
+		if err != nil {
+			continue
+		}
+		rule.re = re
+		m.rules = append(m.rules, rule)
+	}
+	return m
+}
+
+// ReadIgnoreFile reads the ignore file. Missing file gives the matcher which ignores nothing.
+func ReadIgnoreFile(path string) *IgnoreMatcher {
+	data, _ := os.ReadFile(path) // Ignore error, its ok
+	return NewIgnoreMatcher(data)
+}
+
+func ignorePatternToRegexp(pattern string) string {
+	var res strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			res.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			res.WriteString(".*")
+			i++
+		case c == '*':
+			res.WriteString("[^/]*")
+		case c == '?':
+			res.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(pattern[i:], ']')
+			if end < 0 {
+				res.WriteString(regexp.QuoteMeta(string(c)))
+				continue
+			}
+			class := pattern[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			res.WriteString("[" + class + "]")
+			i += end
+		default:
+			res.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return res.String()
+}
+
+// Match checks if the path (relative to the dir of ignore file) is ignored. The path is ignored if one of its parent
+// dirs is ignored, otherwise the last matched pattern wins.
+func (m *IgnoreMatcher) Match(path string, isDir bool) bool {
+	if m == nil || len(m.rules) == 0 {
+		return false
+	}
+	parts := strings.Split(filepath.ToSlash(path), "/")
+	for i := 1; i < len(parts); i++ {
+		if m.match(strings.Join(parts[:i], "/"), true) {
+			return true
+		}
+	}
+	return m.match(strings.Join(parts, "/"), isDir)
+}
+
+func (m *IgnoreMatcher) match(path string, isDir bool) bool {
+	res := false
+	for _, rule := range m.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		if rule.re.MatchString(path) {
+			res = !rule.negate
+		}
+	}
+	return res
+}
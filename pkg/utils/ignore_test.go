@@ -0,0 +1,42 @@
+package utils
+
+import "testing"
+
+func TestIgnoreMatcher(t *testing.T) {
+	m := NewIgnoreMatcher([]byte(`# comment
+stack.yaml
+/top.yaml
+tmp/
+stacks/**/draft-*.yaml
+!stacks/prod/draft-keep.yaml
+*.bak
+!important.bak
+docs/**
+`))
+	cases := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"stack.yaml", false, true},
+		{"stacks/dev/stack.yaml", false, true},
+		{"top.yaml", false, true},
+		{"stacks/top.yaml", false, false},
+		{"tmp", true, true},
+		{"tmp", false, false},
+		{"stacks/tmp/a.yaml", false, true},
+		{"stacks/draft-a.yaml", false, true},
+		{"stacks/dev/eu/draft-a.yaml", false, true},
+		{"stacks/prod/draft-keep.yaml", false, false},
+		{"stacks/prod/main.yaml", false, false},
+		{"a/b/c.bak", false, true},
+		{"a/important.bak", false, false},
+		{"docs/a/b.yaml", false, true},
+		{"project.yaml", false, false},
+	}
+	for _, c := range cases {
+		if res := m.Match(c.path, c.isDir); res != c.want {
+			t.Errorf("Match(%q, %v): expected %v, actual value: %v", c.path, c.isDir, c.want, res)
+		}
+	}
+}
@@ -9,6 +9,8 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/apparentlymart/go-cidr/cidr"
@@ -79,3 +81,27 @@ func GetFileByUrlByte(URL string) ([]byte, error) {
 	}
 	return data.Bytes(), nil
 }
+
+// GetFileByUrlCached downloads the file and saves it to the cacheDir. In offline mode the file is read from the cacheDir.
+func GetFileByUrlCached(URL, cacheDir string, offline bool) ([]byte, error) {
+	fileName, err := URLToFolderName(URL)
+	if err != nil {
+		return nil, err
+	}
+	cachedFile := filepath.Join(cacheDir, fileName)
+	if offline {
+		data, err := os.ReadFile(cachedFile)
+		if err != nil {
+			return nil, fmt.Errorf("offline mode: '%v' is not found in the cache, run cdev without offline mode to download it", URL)
+		}
+		return data, nil
+	}
+	data, err := GetFileByUrlByte(URL)
+	if err != nil {
+		return nil, err
+	}
+	if err = os.MkdirAll(cacheDir, 0755); err != nil {
+		return nil, err
+	}
+	return data, os.WriteFile(cachedFile, data, 0644)
+}
@@ -0,0 +1,506 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/apex/log"
+)
+
+const (
+	ociManifestMediaType    = "application/vnd.oci.image.manifest.v1+json"
+	ociEmptyConfigMediaType = "application/vnd.oci.empty.v1+json"
+	ociLayerMediaType       = "application/vnd.oci.image.layer.v1.tar+gzip"
+	// OCITemplateArtifactType is the artifact type of stack templates pushed by 'cdev template push'.
+	OCITemplateArtifactType = "application/vnd.cluster.dev.template.v1"
+)
+
+// OCIReference describes OCI artifact reference oci://registry/repository[:tag|@digest][//subdir].
+type OCIReference struct {
+	Registry   string
+	Repository string
+	// Reference is the tag or the digest.
+	Reference string
+	SubDir    string
+}
+
+// IsOCIReference checks if the source is OCI artifact reference.
+func IsOCIReference(src string) bool {
+	return strings.HasPrefix(src, "oci://")
+}
+
+// ParseOCIReference parses OCI artifact reference. The tag defaults to 'latest'.
+func ParseOCIReference(src string) (ref OCIReference, err error) {
+	if !IsOCIReference(src) {
+		return ref, fmt.Errorf("parse oci reference: '%v' should start with 'oci://'", src)
+	}
+	rest := strings.TrimPrefix(src, "oci://")
+	rest, ref.SubDir, _ = strings.Cut(rest, "//")
+	var repo string
+	ref.Registry, repo, _ = strings.Cut(rest, "/")
+	if ref.Registry == "" || repo == "" {
+		return ref, fmt.Errorf("parse oci reference: bad reference '%v', expected 'oci://registry/repository:tag'", src)
+	}
+	if name, digest, found := strings.Cut(repo, "@"); found {
+		ref.Repository, ref.Reference = name, digest
+	} else if i := strings.LastIndex(repo, ":"); i > 0 {
+		ref.Repository, ref.Reference = repo[:i], repo[i+1:]
+	} else {
+		ref.Repository, ref.Reference = repo, "latest"
+	}
+	if ref.Repository == "" || ref.Reference == "" {
+		return ref, fmt.Errorf("parse oci reference: bad reference '%v', expected 'oci://registry/repository:tag'", src)
+	}
+	return ref, nil
+}
+
+// Name returns the reference without the tag, digest and subdir.
+func (r OCIReference) Name() string {
+	return r.Registry + "/" + r.Repository
+}
+
+// String returns the reference in the format oci://registry/repository:tag or oci://registry/repository@digest.
+func (r OCIReference) String() string {
+	sep := ":"
+	if strings.HasPrefix(r.Reference, "sha256:") {
+		sep = "@"
+	}
+	return "oci://" + r.Name() + sep + r.Reference
+}
+
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int               `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type ociManifest struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	MediaType     string            `json:"mediaType"`
+	ArtifactType  string            `json:"artifactType,omitempty"`
+	Config        ociDescriptor     `json:"config"`
+	Layers        []ociDescriptor   `json:"layers"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+// ociClient is the minimal client of OCI distribution API. Plain http is used for localhost registries.
+// Credentials are read from docker config file (~/.docker/config.json or $DOCKER_CONFIG/config.json) or from
+// docker credential helpers configured there.
+type ociClient struct {
+	ref   OCIReference
+	http  *http.Client
+	token string
+}
+
+func newOCIClient(ref OCIReference) *ociClient {
+	return &ociClient{
+		ref:  ref,
+		http: &http.Client{Timeout: 5 * time.Minute},
+	}
+}
+
+func (c *ociClient) url(path string) string {
+	scheme := "https"
+	host, _, err := net.SplitHostPort(c.ref.Registry)
+	if err != nil {
+		host = c.ref.Registry
+	}
+	if host == "localhost" || net.ParseIP(host).IsLoopback() {
+		scheme = "http"
+	}
+	return fmt.Sprintf("%s://%s/v2/%s/%s", scheme, c.ref.Registry, c.ref.Repository, path)
+}
+
+// do sends the request, handling registry auth challenge.
+func (c *ociClient) do(method, reqURL string, body []byte, header http.Header) (*http.Response, error) {
+	send := func() (*http.Response, error) {
+		req, err := http.NewRequest(method, reqURL, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		if body != nil {
+			req.ContentLength = int64(len(body))
+		}
+		if c.token != "" {
+			req.Header.Set("Authorization", c.token)
+		}
+		return c.http.Do(req)
+	}
+	resp, err := send()
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	challenge := resp.Header.Get("WWW-Authenticate")
+	resp.Body.Close()
+	if err = c.authorize(challenge); err != nil {
+		return nil, err
+	}
+	return send()
+}
+
+// authorize sets the authorization header value by the WWW-Authenticate challenge.
+func (c *ociClient) authorize(challenge string) error {
+	user, password, err := ociCredentials(c.ref.Registry)
+	if err != nil {
+		return err
+	}
+	scheme, params, _ := strings.Cut(challenge, " ")
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if user == "" || user == ociIdentityTokenUser {
+			return fmt.Errorf("registry %v: authentication required, credentials not found in docker config", c.ref.Registry)
+		}
+		c.token = "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+password))
+		return nil
+	case "bearer":
+	default:
+		return fmt.Errorf("registry %v: unsupported auth challenge '%v'", c.ref.Registry, challenge)
+	}
+	opts := map[string]string{}
+	for _, p := range strings.Split(params, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(p), "=")
+		opts[k] = strings.Trim(v, `"`)
+	}
+	tokenURL, err := url.Parse(opts["realm"])
+	if err != nil || opts["realm"] == "" {
+		return fmt.Errorf("registry %v: bad auth challenge '%v'", c.ref.Registry, challenge)
+	}
+	q := tokenURL.Query()
+	if opts["service"] != "" {
+		q.Set("service", opts["service"])
+	}
+	if opts["scope"] != "" {
+		q.Set("scope", opts["scope"])
+	} else {
+		q.Set("scope", fmt.Sprintf("repository:%s:pull,push", c.ref.Repository))
+	}
+	var req *http.Request
+	if user == ociIdentityTokenUser {
+		// Identity tokens are exchanged with OAuth2 refresh token grant.
+		q.Set("grant_type", "refresh_token")
+		q.Set("refresh_token", password)
+		q.Set("client_id", "cdev")
+		req, err = http.NewRequest(http.MethodPost, tokenURL.String(), strings.NewReader(q.Encode()))
+		if err == nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+	} else {
+		tokenURL.RawQuery = q.Encode()
+		req, err = http.NewRequest(http.MethodGet, tokenURL.String(), nil)
+		if err == nil && user != "" {
+			req.SetBasicAuth(user, password)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("registry %v: get token: %w", c.ref.Registry, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("registry %v: get token: %v", c.ref.Registry, resp.Status)
+	}
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return fmt.Errorf("registry %v: get token: %w", c.ref.Registry, err)
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	c.token = "Bearer " + token.Token
+	return nil
+}
+
+// ociIdentityTokenUser is the user name returned by docker credential helpers for identity (refresh) tokens.
+const ociIdentityTokenUser = "<token>"
+
+// ociCredentials returns the registry credentials from docker config file. Credential helpers configured with
+// 'credHelpers' or 'credsStore' are used, as docker does. Empty user is returned if credentials are not found.
+// An identity token is returned as the password with user '<token>'.
+func ociCredentials(registry string) (string, string, error) {
+	configDir := os.Getenv("DOCKER_CONFIG")
+	if configDir == "" {
+		home, _ := os.UserHomeDir()
+		configDir = filepath.Join(home, ".docker")
+	}
+	data, err := os.ReadFile(filepath.Join(configDir, "config.json"))
+	if err != nil {
+		return "", "", nil
+	}
+	var dockerConfig struct {
+		Auths map[string]struct {
+			Auth          string `json:"auth"`
+			IdentityToken string `json:"identitytoken"`
+		} `json:"auths"`
+		CredsStore  string            `json:"credsStore"`
+		CredHelpers map[string]string `json:"credHelpers"`
+	}
+	if err = json.Unmarshal(data, &dockerConfig); err != nil {
+		return "", "", fmt.Errorf("registry %v: read docker config: %w", registry, err)
+	}
+	helper := dockerConfig.CredHelpers[registry]
+	if helper == "" {
+		helper = dockerConfig.CredsStore
+	}
+	if helper != "" {
+		return ociHelperCredentials(helper, registry)
+	}
+	for _, key := range []string{registry, "https://" + registry, "http://" + registry} {
+		auth, exists := dockerConfig.Auths[key]
+		if !exists {
+			continue
+		}
+		if auth.IdentityToken != "" {
+			return ociIdentityTokenUser, auth.IdentityToken, nil
+		}
+		decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+		if err != nil {
+			return "", "", fmt.Errorf("registry %v: decode auth from docker config: %w", registry, err)
+		}
+		user, password, _ := strings.Cut(string(decoded), ":")
+		return user, password, nil
+	}
+	return "", "", nil
+}
+
+// ociHelperCredentials runs 'docker-credential-<helper> get' to get the registry credentials.
+func ociHelperCredentials(helper, registry string) (string, string, error) {
+	bin := "docker-credential-" + helper
+	cmd := exec.Command(bin, "get")
+	cmd.Stdin = strings.NewReader(registry)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(string(out) + stderr.String())
+		// Helpers report missing credentials in the output.
+		if strings.Contains(strings.ToLower(msg), "credentials not found") {
+			return "", "", nil
+		}
+		return "", "", fmt.Errorf("registry %v: credential helper '%v' configured in docker config: %w: %v", registry, bin, err, msg)
+	}
+	var creds struct {
+		Username string `json:"Username"`
+		Secret   string `json:"Secret"`
+	}
+	if err = json.Unmarshal(out, &creds); err != nil {
+		return "", "", fmt.Errorf("registry %v: credential helper '%v': bad output: %w", registry, bin, err)
+	}
+	return creds.Username, creds.Secret, nil
+}
+
+func ociResponseError(action string, resp *http.Response) error {
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("%v: %v %v", action, resp.Status, strings.TrimSpace(string(msg)))
+}
+
+func (c *ociClient) getManifest() (*ociManifest, string, error) {
+	header := http.Header{"Accept": []string{ociManifestMediaType}}
+	resp, err := c.do(http.MethodGet, c.url("manifests/"+c.ref.Reference), nil, header)
+	if err != nil {
+		return nil, "", fmt.Errorf("get manifest: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", ociResponseError("get manifest", resp)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("get manifest: %w", err)
+	}
+	digest := Sha256Digest(data)
+	if strings.HasPrefix(c.ref.Reference, "sha256:") && digest != c.ref.Reference {
+		return nil, "", fmt.Errorf("get manifest: digest mismatch: expected %v, actual %v", c.ref.Reference, digest)
+	}
+	manifest := &ociManifest{}
+	if err = json.Unmarshal(data, manifest); err != nil {
+		return nil, "", fmt.Errorf("get manifest: %w", err)
+	}
+	return manifest, digest, nil
+}
+
+func (c *ociClient) getBlob(desc ociDescriptor) ([]byte, error) {
+	resp, err := c.do(http.MethodGet, c.url("blobs/"+desc.Digest), nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("get blob: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, ociResponseError("get blob", resp)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("get blob: %w", err)
+	}
+	if err = VerifyChecksum(data, desc.Digest); err != nil {
+		return nil, fmt.Errorf("get blob: %w", err)
+	}
+	return data, nil
+}
+
+func (c *ociClient) pushBlob(data []byte, digest string) error {
+	resp, err := c.do(http.MethodHead, c.url("blobs/"+digest), nil, nil)
+	if err != nil {
+		return fmt.Errorf("push blob: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		log.Debugf("Blob %v already exists", digest)
+		return nil
+	}
+	resp, err = c.do(http.MethodPost, c.url("blobs/uploads/"), nil, nil)
+	if err != nil {
+		return fmt.Errorf("push blob: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		return ociResponseError("push blob", resp)
+	}
+	location, err := resp.Request.URL.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return fmt.Errorf("push blob: bad upload location: %w", err)
+	}
+	q := location.Query()
+	q.Set("digest", digest)
+	location.RawQuery = q.Encode()
+	header := http.Header{"Content-Type": []string{"application/octet-stream"}}
+	resp, err = c.do(http.MethodPut, location.String(), data, header)
+	if err != nil {
+		return fmt.Errorf("push blob: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return ociResponseError("push blob", resp)
+	}
+	return nil
+}
+
+// PullOCITemplate downloads the template artifact and extracts it to the targetDir/templateName/<digest> dir.
+// Artifacts are stored by the manifest digest, so the existing dir is reused. Returns the template dir
+// (with reference subdir) and the manifest digest.
+func PullOCITemplate(ref OCIReference, targetDir, templateName string) (string, string, error) {
+	log.Debugf("Pulling template artifact: %v", ref)
+	c := newOCIClient(ref)
+	manifest, digest, err := c.getManifest()
+	if err != nil {
+		return "", "", fmt.Errorf("pull %v: %w", ref, err)
+	}
+	artifactDir := filepath.Join(targetDir, templateName, strings.TrimPrefix(digest, "sha256:"))
+	if IsDir(artifactDir) {
+		return filepath.Join(artifactDir, ref.SubDir), digest, nil
+	}
+	var layer *ociDescriptor
+	for i := range manifest.Layers {
+		if manifest.Layers[i].MediaType == ociLayerMediaType {
+			layer = &manifest.Layers[i]
+			break
+		}
+	}
+	if layer == nil {
+		return "", "", fmt.Errorf("pull %v: the artifact has no layer of type %v", ref, ociLayerMediaType)
+	}
+	data, err := c.getBlob(*layer)
+	if err != nil {
+		return "", "", fmt.Errorf("pull %v: %w", ref, err)
+	}
+	tmpDir := artifactDir + ".tmp"
+	os.RemoveAll(tmpDir)
+	if err = ExtractArchive(data, tmpDir); err != nil {
+		os.RemoveAll(tmpDir)
+		return "", "", fmt.Errorf("pull %v: %w", ref, err)
+	}
+	if err = os.Rename(tmpDir, artifactDir); err != nil {
+		return "", "", fmt.Errorf("pull %v: %w", ref, err)
+	}
+	return filepath.Join(artifactDir, ref.SubDir), digest, nil
+}
+
+// GetCachedOCITemplate returns the template artifact pulled before, without access to the registry. The reference
+// should be the digest.
+func GetCachedOCITemplate(ref OCIReference, targetDir, templateName string) (string, error) {
+	artifactDir := filepath.Join(targetDir, templateName, strings.TrimPrefix(ref.Reference, "sha256:"))
+	if !strings.HasPrefix(ref.Reference, "sha256:") || !IsDir(artifactDir) {
+		return "", fmt.Errorf("offline mode: '%v' is not found in the cache, run cdev without offline mode to download it", ref)
+	}
+	return filepath.Join(artifactDir, ref.SubDir), nil
+}
+
+// PushOCITemplate packs the template dir and pushes it to the registry as OCI artifact. Returns the manifest digest.
+func PushOCITemplate(dir, dest string) (string, error) {
+	ref, err := ParseOCIReference(dest)
+	if err != nil {
+		return "", err
+	}
+	if ref.SubDir != "" || strings.HasPrefix(ref.Reference, "sha256:") {
+		return "", fmt.Errorf("push: destination should be 'oci://registry/repository:tag'")
+	}
+	layerData, err := TarGzDir(dir)
+	if err != nil {
+		return "", fmt.Errorf("push %v: %w", ref, err)
+	}
+	configData := []byte("{}")
+	manifest := ociManifest{
+		SchemaVersion: 2,
+		MediaType:     ociManifestMediaType,
+		ArtifactType:  OCITemplateArtifactType,
+		Config: ociDescriptor{
+			MediaType: ociEmptyConfigMediaType,
+			Digest:    Sha256Digest(configData),
+			Size:      len(configData),
+		},
+		Layers: []ociDescriptor{{
+			MediaType: ociLayerMediaType,
+			Digest:    Sha256Digest(layerData),
+			Size:      len(layerData),
+			Annotations: map[string]string{
+				"org.opencontainers.image.title": filepath.Base(ref.Repository) + ".tar.gz",
+			},
+		}},
+		Annotations: map[string]string{
+			"org.opencontainers.image.created": time.Now().UTC().Format(time.RFC3339),
+		},
+	}
+	c := newOCIClient(ref)
+	for _, blob := range []struct {
+		data   []byte
+		digest string
+	}{{configData, manifest.Config.Digest}, {layerData, manifest.Layers[0].Digest}} {
+		if err = c.pushBlob(blob.data, blob.digest); err != nil {
+			return "", fmt.Errorf("push %v: %w", ref, err)
+		}
+	}
+	manifestData, err := json.Marshal(manifest)
+	if err != nil {
+		return "", err
+	}
+	header := http.Header{"Content-Type": []string{ociManifestMediaType}}
+	resp, err := c.do(http.MethodPut, c.url("manifests/"+ref.Reference), manifestData, header)
+	if err != nil {
+		return "", fmt.Errorf("push %v: %w", ref, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return "", fmt.Errorf("push %v: %w", ref, ociResponseError("put manifest", resp))
+	}
+	return Sha256Digest(manifestData), nil
+}
@@ -0,0 +1,178 @@
+package utils
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testRegistry is the in-memory registry which implements the part of OCI distribution API used by cdev.
+type testRegistry struct {
+	mu        sync.Mutex
+	blobs     map[string][]byte
+	manifests map[string][]byte
+}
+
+func (r *testRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	path := strings.TrimPrefix(req.URL.Path, "/v2/")
+	switch {
+	case strings.Contains(path, "/blobs/uploads/"):
+		if req.Method == http.MethodPost {
+			w.Header().Set("Location", "/v2/"+path+"upload-1")
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		data, _ := io.ReadAll(req.Body)
+		r.blobs[req.URL.Query().Get("digest")] = data
+		w.WriteHeader(http.StatusCreated)
+	case strings.Contains(path, "/blobs/"):
+		data, exists := r.blobs[path[strings.LastIndex(path, "/")+1:]]
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(data)
+	case strings.Contains(path, "/manifests/"):
+		key := strings.Replace(path, "/manifests/", ":", 1)
+		if req.Method == http.MethodPut {
+			data, _ := io.ReadAll(req.Body)
+			r.manifests[key] = data
+			r.manifests[key[:strings.LastIndex(key, ":")]+":"+Sha256Digest(data)] = data
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		data, exists := r.manifests[key]
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", ociManifestMediaType)
+		w.Write(data)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestOCITemplatePushPull(t *testing.T) {
+	server := httptest.NewServer(&testRegistry{blobs: map[string][]byte{}, manifests: map[string][]byte{}})
+	defer server.Close()
+	registry := strings.TrimPrefix(server.URL, "http://")
+
+	srcDir := t.TempDir()
+	files := map[string]string{
+		"template.yaml":        "kind: StackTemplate\nname: test\n",
+		"modules/main.tf":      "# module\n",
+		"modules/sub/vars.tf":  "# vars\n",
+		".git/ignored-by-push": "git",
+	}
+	for fn, data := range files {
+		os.MkdirAll(filepath.Join(srcDir, filepath.Dir(fn)), 0755)
+		if err := os.WriteFile(filepath.Join(srcDir, fn), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	digest, err := PushOCITemplate(srcDir, "oci://"+registry+"/templates/test:1.0.0")
+	if err != nil {
+		t.Fatalf("push: %v", err)
+	}
+
+	ref, err := ParseOCIReference("oci://" + registry + "/templates/test:1.0.0//modules")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cacheDir := t.TempDir()
+	dir, pulledDigest, err := PullOCITemplate(ref, cacheDir, "test")
+	if err != nil {
+		t.Fatalf("pull: %v", err)
+	}
+	if pulledDigest != digest {
+		t.Errorf("pull: expected digest %v, actual value: %v", digest, pulledDigest)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "sub", "vars.tf"))
+	if err != nil || string(data) != "# vars\n" {
+		t.Errorf("pull: unexpected content of sub/vars.tf: %q, %v", data, err)
+	}
+	if Exists(filepath.Join(dir, "..", ".git")) {
+		t.Errorf("pull: .git dir should not be pushed")
+	}
+
+	ref.Reference = digest
+	cachedDir, err := GetCachedOCITemplate(ref, cacheDir, "test")
+	if err != nil || cachedDir != dir {
+		t.Errorf("cached: expected %v, actual value: %v, %v", dir, cachedDir, err)
+	}
+}
+
+func TestOCICredentials(t *testing.T) {
+	binDir := t.TempDir()
+	helper := `#!/bin/sh
+read registry
+case "$registry" in
+  ecr.example.com) echo '{"ServerURL":"ecr.example.com","Username":"AWS","Secret":"pass"}';;
+  broken.example.com) echo "helper failure" >&2; exit 2;;
+  *) echo "credentials not found in native keychain"; exit 1;;
+esac
+`
+	if err := os.WriteFile(filepath.Join(binDir, "docker-credential-test"), []byte(helper), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	configDir := t.TempDir()
+	t.Setenv("DOCKER_CONFIG", configDir)
+	config := `{
+  "auths": {"ghcr.io": {"auth": "dXNlcjpzZWNyZXQ="}, "acr.example.com": {"identitytoken": "refresh"}},
+  "credHelpers": {"ecr.example.com": "test", "broken.example.com": "test", "other.example.com": "test"}
+}`
+	if err := os.WriteFile(filepath.Join(configDir, "config.json"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		registry string
+		user     string
+		password string
+		fails    bool
+	}{
+		{"ghcr.io", "user", "secret", false},
+		{"acr.example.com", ociIdentityTokenUser, "refresh", false},
+		{"ecr.example.com", "AWS", "pass", false},
+		{"other.example.com", "", "", false},
+		{"broken.example.com", "", "", true},
+		{"unknown.example.com", "", "", false},
+	}
+	for _, c := range cases {
+		user, password, err := ociCredentials(c.registry)
+		if (err != nil) != c.fails {
+			t.Errorf("%v: unexpected error: %v", c.registry, err)
+		}
+		if user != c.user || password != c.password {
+			t.Errorf("%v: expected %v:%v, actual value: %v:%v", c.registry, c.user, c.password, user, password)
+		}
+	}
+}
+
+func TestParseArchiveSource(t *testing.T) {
+	cases := []struct {
+		src     string
+		archive ArchiveSource
+		ok      bool
+	}{
+		{"https://example.com/t.tar.gz", ArchiveSource{URL: "https://example.com/t.tar.gz"}, true},
+		{"https://example.com/t.zip//sub/dir?checksum=sha256:abc", ArchiveSource{URL: "https://example.com/t.zip", SubDir: "sub/dir", Checksum: "sha256:abc"}, true},
+		{"http://example.com/t.tgz?token=x&checksum=sha256:abc", ArchiveSource{URL: "http://example.com/t.tgz?token=x", Checksum: "sha256:abc"}, true},
+		{"https://github.com/org/repo//template?ref=v1", ArchiveSource{}, false},
+		{"./local.tar.gz", ArchiveSource{}, false},
+	}
+	for _, c := range cases {
+		archive, ok := ParseArchiveSource(c.src)
+		if ok != c.ok || (ok && archive != c.archive) {
+			t.Errorf("ParseArchiveSource(%q): expected %+v %v, actual value: %+v %v", c.src, c.archive, c.ok, archive, ok)
+		}
+	}
+}
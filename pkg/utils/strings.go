@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"math/rand"
 	"regexp"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -43,3 +45,23 @@ func URLToFolderName(URL string) (string, error) {
 	newStr := reg.ReplaceAllString(URL, "_")
 	return newStr, nil
 }
+
+// ShellQuote quotes the string with single quotes to pass it to the shell as one word.
+func ShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// FlagArgs returns shell-quoted 'key=value' pairs, each prefixed with the flag, e.g. " --set 'a=b' --set 'c=d'".
+// Keys are sorted, so the result is stable.
+func FlagArgs(flag string, values map[string]interface{}) string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	res := ""
+	for _, key := range keys {
+		res = fmt.Sprintf("%s %s %s", res, flag, ShellQuote(fmt.Sprintf("%s=%v", key, values[key])))
+	}
+	return res
+}
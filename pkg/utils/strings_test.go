@@ -0,0 +1,18 @@
+package utils
+
+import "testing"
+
+func TestFlagArgs(t *testing.T) {
+	values := map[string]interface{}{
+		"image.tag": "v1",
+		"replicas":  2,
+		"msg":       "it's ok",
+	}
+	want := ` --set 'image.tag=v1' --set 'msg=it'\''s ok' --set 'replicas=2'`
+	if got := FlagArgs("--set", values); got != want {
+		t.Errorf("FlagArgs() = %q, want %q", got, want)
+	}
+	if got := FlagArgs("--set", nil); got != "" {
+		t.Errorf("FlagArgs(nil) = %q, want empty", got)
+	}
+}
@@ -3,6 +3,7 @@ package utils
 import (
 	"bytes"
 	"fmt"
+	"strconv"
 
 	"gopkg.in/yaml.v3"
 )
@@ -33,3 +34,76 @@ func ReadYAML(objData []byte) (res map[string]interface{}, err error) {
 	err = ResolveYamlError(objData, err)
 	return
 }
+
+// FindYAMLObjectLine searches for the object with field 'name' equal to objName in multi-document YAML data
+// and returns the line number of the node addressed by path (keys of nested maps).
+// If the full path is not found, the line of the deepest existing parent is returned. Returns 0 if the object is not found.
+func FindYAMLObjectLine(data []byte, objName string, path ...string) int {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc yaml.Node
+		if err := dec.Decode(&doc); err != nil {
+			return 0
+		}
+		if len(doc.Content) == 0 {
+			continue
+		}
+		root := doc.Content[0]
+		nameNode := yamlMapValue(root, "name")
+		if nameNode == nil || nameNode.Value != objName {
+			continue
+		}
+		return yamlPathLine(root, path)
+	}
+}
+
+// FindYAMLLine returns the line number of the node addressed by path (keys of nested maps) in the first YAML document.
+// If the full path is not found, the line of the deepest existing parent is returned. Returns 0 if data can't be parsed.
+func FindYAMLLine(data []byte, path ...string) int {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil || len(doc.Content) == 0 {
+		return 0
+	}
+	return yamlPathLine(doc.Content[0], path)
+}
+
+func yamlPathLine(root *yaml.Node, path []string) int {
+	line := root.Line
+	node := root
+	for _, key := range path {
+		keyNode, valNode := yamlMapEntry(node, key)
+		if keyNode == nil {
+			break
+		}
+		line = keyNode.Line
+		node = valNode
+	}
+	return line
+}
+
+func yamlMapEntry(node *yaml.Node, key string) (keyNode, valNode *yaml.Node) {
+	if node == nil {
+		return nil, nil
+	}
+	if node.Kind == yaml.SequenceNode {
+		i, err := strconv.Atoi(key)
+		if err != nil || i < 0 || i >= len(node.Content) {
+			return nil, nil
+		}
+		return node.Content[i], node.Content[i]
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i], node.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+func yamlMapValue(node *yaml.Node, key string) *yaml.Node {
+	_, val := yamlMapEntry(node, key)
+	return val
+}